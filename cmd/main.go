@@ -1,15 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"location-api/configs"
 	"location-api/internal"
+	"location-api/internal/cache"
 	"os"
 
 	logger "github.com/can-zanat/gologger"
 )
 
-const serverPort = ":96"
-
 func main() {
 	if err := run(); err != nil {
 		fmt.Println(err)
@@ -18,6 +20,25 @@ func main() {
 }
 
 func run() error {
+	config, err := configs.Load(os.Args[1:])
+	if errors.Is(err, configs.ErrHelp) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if config.PrintConfig {
+		if err := config.Print(os.Stdout); err != nil {
+			return err
+		}
+
+		return config.Validate()
+	}
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
 	loggerInfoLevel := logger.NewWithLogLevel("info")
 	defer func() {
 		err := loggerInfoLevel.Sync()
@@ -26,11 +47,47 @@ func run() error {
 		}
 	}()
 
-	store := internal.NewStore()
-	service := internal.NewService(store)
-	handler := internal.NewHandler(service)
+	locationCache, err := cache.New(cache.Options{
+		Driver:              config.Cache.Driver,
+		RedisAddr:           config.Cache.Redis.Addr,
+		RedisPassword:       config.Cache.Redis.Password,
+		RedisDB:             config.Cache.Redis.DB,
+		MaxEntries:          config.Cache.Memory.MaxEntries,
+		Invalidation:        config.Cache.Invalidation.Enabled,
+		InvalidationChannel: config.Cache.Invalidation.Channel,
+		FallbackTTL:         config.Cache.Invalidation.FallbackTTL,
+	})
+	if err != nil {
+		return err
+	}
+
+	if distributed, ok := locationCache.(*cache.DistributedCache); ok {
+		invalidationCtx, stopInvalidation := context.WithCancel(context.Background())
+		defer stopInvalidation()
+
+		go distributed.Run(invalidationCtx)
+	}
+
+	store := internal.NewStore(config.MongoDB.URI, locationCache,
+		internal.WithDBTimeout(config.MongoDB.Timeout),
+		internal.WithRoutesCache(config.Cache.TTL, config.Cache.StaleTTL),
+	)
+	service := internal.NewService(store, locationCache,
+		internal.WithLocationsCache(config.Cache.TTL, config.Cache.StaleTTL),
+	)
+	handler := internal.NewHandler(service, internal.WithDistanceMatrixLimits(
+		config.DistanceMatrix.MaxElements,
+		config.DistanceMatrix.StreamThreshold,
+	))
+
+	if config.Archive.Retention > 0 && config.Archive.PurgeInterval > 0 {
+		purgeCtx, cancelPurge := context.WithCancel(context.Background())
+		defer cancelPurge()
+
+		go service.RunArchivePurge(purgeCtx, config.Archive.Retention, config.Archive.PurgeInterval)
+	}
 
-	New(serverPort, handler, loggerInfoLevel).Run()
+	New(config.Server, handler, loggerInfoLevel).Run()
 
 	return nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"location-api/configs"
 	"os"
 	"os/signal"
 	"syscall"
@@ -16,9 +17,6 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
-const maxRequestPerSecond = 1000
-const maxRequestPerIP = 2
-
 type Handler interface {
 	RegisterRoutes(app *fiber.App)
 }
@@ -29,15 +27,15 @@ type Server struct {
 	logger *zap.Logger
 }
 
-func New(port string, handler Handler, logger *zap.Logger) Server {
+func New(config configs.ServerConfig, handler Handler, logger *zap.Logger) Server {
 	app := fiber.New(fiber.Config{})
-	server := Server{app: app, port: port, logger: logger}
+	server := Server{app: app, port: config.Port, logger: logger}
 
 	server.app.Use(recover.New())
 	server.app.Use(cors.New())
 
 	server.app.Use(limiter.New(limiter.Config{
-		Max:        maxRequestPerSecond,
+		Max:        config.MaxRequestPerSecond,
 		Expiration: time.Second,
 		LimitReached: func(c *fiber.Ctx) error {
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
@@ -47,7 +45,7 @@ func New(port string, handler Handler, logger *zap.Logger) Server {
 	}))
 
 	server.app.Use(limiter.New(limiter.Config{
-		Max:        maxRequestPerIP,
+		Max:        config.MaxRequestPerIP,
 		Expiration: time.Second,
 		KeyGenerator: func(c *fiber.Ctx) string {
 			return c.IP()
@@ -1,28 +1,298 @@
 package configs
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
+const defaultConfigFile = ".config/local.yaml"
+const redacted = "REDACTED"
+
+// ErrHelp is returned by Load when the usage was asked for with --help.
+var ErrHelp = pflag.ErrHelp
+
 type Config struct {
-	MongoDB struct {
-		URI string `mapstructure:"uri"`
-	} `mapstructure:"mongoDB"`
+	Server         ServerConfig         `mapstructure:"server" yaml:"server"`
+	MongoDB        MongoDBConfig        `mapstructure:"mongoDB" yaml:"mongoDB"`
+	DistanceMatrix DistanceMatrixConfig `mapstructure:"distanceMatrix" yaml:"distanceMatrix"`
+	Archive        ArchiveConfig        `mapstructure:"archive" yaml:"archive"`
+	Cache          CacheConfig          `mapstructure:"cache" yaml:"cache"`
+
+	// ConfigFile and PrintConfig only come from flags and the environment.
+	ConfigFile  string `mapstructure:"-" yaml:"-"`
+	PrintConfig bool   `mapstructure:"-" yaml:"-"`
+}
+
+type ServerConfig struct {
+	Port                string `mapstructure:"port" yaml:"port"`
+	MaxRequestPerSecond int    `mapstructure:"maxRequestPerSecond" yaml:"maxRequestPerSecond"`
+	MaxRequestPerIP     int    `mapstructure:"maxRequestPerIP" yaml:"maxRequestPerIP"`
+}
+
+type MongoDBConfig struct {
+	URI     string        `mapstructure:"uri" yaml:"uri"`
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+type DistanceMatrixConfig struct {
+	MaxElements     int `mapstructure:"maxElements" yaml:"maxElements"`
+	StreamThreshold int `mapstructure:"streamThreshold" yaml:"streamThreshold"`
+}
+
+type ArchiveConfig struct {
+	Retention     time.Duration `mapstructure:"retention" yaml:"retention"`
+	PurgeInterval time.Duration `mapstructure:"purgeInterval" yaml:"purgeInterval"`
+}
+
+type CacheConfig struct {
+	Driver       string                  `mapstructure:"driver" yaml:"driver"`
+	TTL          time.Duration           `mapstructure:"ttl" yaml:"ttl"`
+	StaleTTL     time.Duration           `mapstructure:"staleTTL" yaml:"staleTTL"`
+	Redis        RedisConfig             `mapstructure:"redis" yaml:"redis"`
+	Memory       MemoryCacheConfig       `mapstructure:"memory" yaml:"memory"`
+	Invalidation CacheInvalidationConfig `mapstructure:"invalidation" yaml:"invalidation"`
+}
+
+type RedisConfig struct {
+	Addr     string `mapstructure:"addr" yaml:"addr"`
+	Password string `mapstructure:"password" yaml:"password"`
+	DB       int    `mapstructure:"db" yaml:"db"`
+}
+
+type MemoryCacheConfig struct {
+	MaxEntries int `mapstructure:"maxEntries" yaml:"maxEntries"`
+}
+
+type CacheInvalidationConfig struct {
+	Enabled     bool          `mapstructure:"enabled" yaml:"enabled"`
+	Channel     string        `mapstructure:"channel" yaml:"channel"`
+	FallbackTTL time.Duration `mapstructure:"fallbackTTL" yaml:"fallbackTTL"`
+}
+
+// setting ties a key of the config file to its flag, environment variables and
+// default. The type of the default is the type of the flag.
+type setting struct {
+	key   string
+	flag  string
+	env   []string
+	def   interface{}
+	usage string
 }
 
-func LoadConfig() (*Config, error) {
-	viper.SetConfigName("local")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".config")
+var settings = []setting{
+	{"server.port", "port", []string{"PORT"}, ":96", "address the HTTP server listens on"},
+	{"server.maxRequestPerSecond", "max-request-per-second", []string{"MAX_REQUEST_PER_SECOND"}, 1000, "requests per second served in total"},
+	{"server.maxRequestPerIP", "max-request-per-ip", []string{"MAX_REQUEST_PER_IP"}, 2, "requests per second served to one IP"},
+	{"mongoDB.uri", "mongo-uri", []string{"MONGO_URI"}, "", "MongoDB connection string"},
+	{"mongoDB.timeout", "db-timeout", []string{"DB_TIMEOUT"}, 5 * time.Minute, "timeout of database operations"},
+	{"distanceMatrix.maxElements", "matrix-max-elements", []string{"MATRIX_MAX_ELEMENTS"}, 250000, "largest distance matrix served"},
+	{"distanceMatrix.streamThreshold", "matrix-stream-threshold", []string{"MATRIX_STREAM_THRESHOLD"}, 10000, "distance matrix size above which it is streamed"},
+	{"archive.retention", "archive-retention", []string{"ARCHIVE_RETENTION"}, 720 * time.Hour, "age at which archived locations are purged, 0 to keep them"},
+	{"archive.purgeInterval", "archive-purge-interval", []string{"ARCHIVE_PURGE_INTERVAL"}, time.Hour, "how often archived locations are purged, 0 to never purge"},
+	{"cache.driver", "cache-driver", []string{"CACHE_DRIVER"}, "redis", "cache driver, redis or memory"},
+	{"cache.ttl", "cache-ttl", []string{"CACHE_TTL"}, 30 * time.Second, "how long cached responses are fresh"},
+	{"cache.staleTTL", "cache-stale-ttl", []string{"CACHE_STALE_TTL"}, 5 * time.Minute, "how long stale responses are served while refreshing"},
+	{"cache.redis.addr", "redis-addr", []string{"REDIS_ADDR"}, "redis:6379", "Redis address"},
+	{"cache.redis.password", "redis-password", []string{"REDIS_PASSWORD"}, "", "Redis password"},
+	{"cache.redis.db", "redis-db", []string{"REDIS_DB"}, 0, "Redis database"},
+	{"cache.memory.maxEntries", "cache-max-entries", []string{"CACHE_MAX_ENTRIES"}, 10000, "entries held by the memory cache"},
+	{"cache.invalidation.enabled", "cache-invalidation", []string{"CACHE_INVALIDATION"}, false, "share memory cache deletes over Redis"},
+	{"cache.invalidation.channel", "cache-invalidation-channel", []string{"CACHE_INVALIDATION_CHANNEL"}, "location-api:cache-invalidation", "Redis channel of cache deletes"},
+	{"cache.invalidation.fallbackTTL", "cache-fallback-ttl", []string{"CACHE_FALLBACK_TTL"}, 5 * time.Second, "cache TTL while deletes cannot be heard"},
+}
+
+// Load builds the config from args, the environment, the config file and the
+// defaults, in that order of precedence. The config file is .config/local.yaml
+// unless --config or CONFIG_FILE names another; only a named file has to exist.
+func Load(args []string) (*Config, error) {
+	v := viper.New()
+	flags := pflag.NewFlagSet("location-api", pflag.ContinueOnError)
+
+	configFile := flags.String("config", "", "config file (default "+defaultConfigFile+", env CONFIG_FILE)")
+	printConfig := flags.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+
+	for _, s := range settings {
+		addFlag(flags, s)
+
+		v.SetDefault(s.key, s.def)
+
+		if err := v.BindEnv(append([]string{s.key}, s.env...)...); err != nil {
+			return nil, err
+		}
 
-	if err := viper.ReadInConfig(); err != nil {
+		if err := v.BindPFlag(s.key, flags.Lookup(s.flag)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
 		return nil, err
 	}
 
-	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	file := *configFile
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+
+	if err := readConfigFile(v, file); err != nil {
 		return nil, err
 	}
 
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	config.ConfigFile = file
+	if file == "" {
+		config.ConfigFile = defaultConfigFile
+	}
+
+	config.PrintConfig = *printConfig
+
 	return &config, nil
 }
+
+func addFlag(flags *pflag.FlagSet, s setting) {
+	usage := s.usage + " (env " + strings.Join(s.env, ", ") + ")"
+
+	switch def := s.def.(type) {
+	case string:
+		flags.String(s.flag, def, usage)
+	case int:
+		flags.Int(s.flag, def, usage)
+	case bool:
+		flags.Bool(s.flag, def, usage)
+	case time.Duration:
+		flags.Duration(s.flag, def, usage)
+	}
+}
+
+func readConfigFile(v *viper.Viper, file string) error {
+	v.SetConfigType("yaml")
+
+	if file == "" {
+		if _, err := os.Stat(defaultConfigFile); err != nil {
+			return nil
+		}
+
+		file = defaultConfigFile
+	}
+
+	v.SetConfigFile(file)
+
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("config: cannot read %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// Validate reports every setting that cannot be used, naming the flag and the
+// environment variable that set it.
+func (c *Config) Validate() error {
+	var errs []error
+
+	check := func(ok bool, key, problem string) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("config: %s %s", describe(key), problem))
+		}
+	}
+
+	check(validPort(c.Server.Port), "server.port", "must be an address such as :96 or 0.0.0.0:96")
+	check(c.Server.MaxRequestPerSecond > 0, "server.maxRequestPerSecond", "must be at least 1")
+	check(c.Server.MaxRequestPerIP > 0, "server.maxRequestPerIP", "must be at least 1")
+	check(validMongoURI(c.MongoDB.URI), "mongoDB.uri", "must be a mongodb:// or mongodb+srv:// URI")
+	check(c.MongoDB.Timeout > 0, "mongoDB.timeout", "must be positive")
+	check(c.DistanceMatrix.MaxElements > 0, "distanceMatrix.maxElements", "must be at least 1")
+	check(c.DistanceMatrix.StreamThreshold > 0, "distanceMatrix.streamThreshold", "must be at least 1")
+	check(c.Archive.Retention >= 0, "archive.retention", "must not be negative")
+	check(c.Archive.PurgeInterval >= 0, "archive.purgeInterval", "must not be negative")
+	check(c.Cache.Driver == "redis" || c.Cache.Driver == "memory", "cache.driver", "must be redis or memory")
+	check(c.Cache.TTL > 0, "cache.ttl", "must be positive")
+	check(c.Cache.StaleTTL >= 0, "cache.staleTTL", "must not be negative")
+	check(c.Cache.Memory.MaxEntries > 0, "cache.memory.maxEntries", "must be at least 1")
+	check(c.Cache.Redis.DB >= 0, "cache.redis.db", "must not be negative")
+	check(c.Cache.Invalidation.FallbackTTL > 0, "cache.invalidation.fallbackTTL", "must be positive")
+
+	if c.Cache.Driver == "redis" || c.Cache.Invalidation.Enabled {
+		_, _, err := net.SplitHostPort(c.Cache.Redis.Addr)
+		check(err == nil, "cache.redis.addr", "must be a host:port address")
+	}
+
+	if c.Cache.Invalidation.Enabled {
+		check(c.Cache.Invalidation.Channel != "", "cache.invalidation.channel", "must not be empty")
+	}
+
+	return errors.Join(errs...)
+}
+
+func describe(key string) string {
+	for _, s := range settings {
+		if s.key == key {
+			return fmt.Sprintf("%s (--%s, %s)", key, s.flag, strings.Join(s.env, ", "))
+		}
+	}
+
+	return key
+}
+
+func validPort(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	n, err := strconv.Atoi(port)
+
+	return err == nil && n > 0 && n <= 65535
+}
+
+func validMongoURI(uri string) bool {
+	u, err := url.Parse(uri)
+
+	return err == nil && (u.Scheme == "mongodb" || u.Scheme == "mongodb+srv") && u.Host != ""
+}
+
+// Redacted returns a copy of the config without passwords.
+func (c *Config) Redacted() Config {
+	config := *c
+
+	if u, err := url.Parse(c.MongoDB.URI); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			config.MongoDB.URI = u.String()
+		}
+	}
+
+	if c.Cache.Redis.Password != "" {
+		config.Cache.Redis.Password = redacted
+	}
+
+	return config
+}
+
+// Print writes the redacted config to w in the format of the config file.
+func (c *Config) Print(w io.Writer) error {
+	config := c.Redacted()
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&config); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
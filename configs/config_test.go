@@ -0,0 +1,141 @@
+package configs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	return file
+}
+
+// chdir moves the test where there is no .config/local.yaml to read.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("should fall back to the defaults", func(t *testing.T) {
+		chdir(t, t.TempDir())
+
+		config, err := Load(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, ":96", config.Server.Port)
+		assert.Equal(t, 1000, config.Server.MaxRequestPerSecond)
+		assert.Equal(t, 5*time.Minute, config.MongoDB.Timeout)
+		assert.Equal(t, "redis", config.Cache.Driver)
+		assert.Equal(t, 30*time.Second, config.Cache.TTL)
+		assert.Equal(t, defaultConfigFile, config.ConfigFile)
+	})
+
+	t.Run("should prefer flags to env to the file to the defaults", func(t *testing.T) {
+		file := writeConfigFile(t, `
+server:
+  port: ":8080"
+  maxRequestPerIP: 5
+mongoDB:
+  uri: "mongodb://file:27017"
+cache:
+  ttl: 10s
+`)
+
+		t.Setenv("MONGO_URI", "mongodb://env:27017")
+		t.Setenv("CACHE_TTL", "20s")
+
+		config, err := Load([]string{"--config", file, "--cache-ttl", "40s"})
+		assert.NoError(t, err)
+		assert.Equal(t, ":8080", config.Server.Port)
+		assert.Equal(t, 5, config.Server.MaxRequestPerIP)
+		assert.Equal(t, 1000, config.Server.MaxRequestPerSecond)
+		assert.Equal(t, "mongodb://env:27017", config.MongoDB.URI)
+		assert.Equal(t, 40*time.Second, config.Cache.TTL)
+		assert.Equal(t, file, config.ConfigFile)
+	})
+
+	t.Run("should read the file named by CONFIG_FILE", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", writeConfigFile(t, "cache:\n  driver: memory\n"))
+
+		config, err := Load([]string{"--print-config"})
+		assert.NoError(t, err)
+		assert.Equal(t, "memory", config.Cache.Driver)
+		assert.True(t, config.PrintConfig)
+	})
+
+	t.Run("should return an error for a missing file or an unknown flag", func(t *testing.T) {
+		_, err := Load([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")})
+		assert.Error(t, err)
+
+		_, err = Load([]string{"--unknown"})
+		assert.Error(t, err)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	t.Run("should accept the defaults with a database", func(t *testing.T) {
+		config, err := Load([]string{"--mongo-uri", "mongodb://root:secret@db:27017/location"})
+		assert.NoError(t, err)
+		assert.NoError(t, config.Validate())
+	})
+
+	t.Run("should report every invalid setting with its flag and env", func(t *testing.T) {
+		config, err := Load([]string{"--port", "96", "--cache-driver", "memcached", "--db-timeout", "0s"})
+		assert.NoError(t, err)
+
+		err = config.Validate()
+		assert.EqualError(t, err, "config: server.port (--port, PORT) must be an address such as :96 or 0.0.0.0:96\n"+
+			"config: mongoDB.uri (--mongo-uri, MONGO_URI) must be a mongodb:// or mongodb+srv:// URI\n"+
+			"config: mongoDB.timeout (--db-timeout, DB_TIMEOUT) must be positive\n"+
+			"config: cache.driver (--cache-driver, CACHE_DRIVER) must be redis or memory")
+	})
+
+	t.Run("should only need Redis for the redis driver or invalidation", func(t *testing.T) {
+		config, err := Load([]string{"--mongo-uri", "mongodb://db", "--cache-driver", "memory", "--redis-addr", ""})
+		assert.NoError(t, err)
+		assert.NoError(t, config.Validate())
+
+		config.Cache.Invalidation.Enabled = true
+		assert.EqualError(t, config.Validate(), "config: cache.redis.addr (--redis-addr, REDIS_ADDR) must be a host:port address")
+	})
+}
+
+func TestConfig_Print(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	config, err := Load([]string{"--mongo-uri", "mongodb://root:secret@db:27017/location", "--redis-password", "hunter2"})
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, config.Print(&out))
+
+	assert.Contains(t, out.String(), "uri: mongodb://root:REDACTED@db:27017/location")
+	assert.Contains(t, out.String(), "password: REDACTED")
+	assert.Contains(t, out.String(), "ttl: 30s")
+	assert.NotContains(t, out.String(), "secret")
+	assert.NotContains(t, out.String(), "hunter2")
+	assert.Equal(t, "mongodb://root:secret@db:27017/location", config.MongoDB.URI)
+}
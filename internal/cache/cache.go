@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DriverRedis  = "redis"
+	DriverMemory = "memory"
+)
+
+// ErrMiss is returned by Get when the key is not cached or has expired.
+var ErrMiss = errors.New("cache: key not found")
+
+// Cache stores values as JSON under string keys. An expiration of zero keeps a
+// value until it is deleted or, for bounded caches, evicted.
+type Cache interface {
+	Get(key string, dest interface{}) error
+	Set(key string, value interface{}, expiration time.Duration) error
+	Delete(key string) error
+	DeleteByPrefix(prefix string) error
+}
+
+// Options holds the settings of every driver; only those of the chosen driver
+// are used. Invalidation makes the memory driver share its deletes with other
+// instances through Redis.
+type Options struct {
+	Driver              string
+	RedisAddr           string
+	RedisPassword       string
+	RedisDB             int
+	MaxEntries          int
+	Invalidation        bool
+	InvalidationChannel string
+	FallbackTTL         time.Duration
+}
+
+// New returns the cache of the configured driver. An empty driver means Redis,
+// which is what the service always used. A DistributedCache has to be Run.
+func New(opts Options) (Cache, error) {
+	switch opts.Driver {
+	case DriverRedis, "":
+		return NewRedisCache(NewRedisClient(opts.RedisAddr, opts.RedisPassword, opts.RedisDB)), nil
+	case DriverMemory:
+		if opts.Invalidation {
+			client := NewRedisClient(opts.RedisAddr, opts.RedisPassword, opts.RedisDB)
+			return NewDistributedCache(NewMemoryCache(opts.MaxEntries), client, opts.InvalidationChannel, opts.FallbackTTL), nil
+		}
+
+		return NewMemoryCache(opts.MaxEntries), nil
+	default:
+		return nil, fmt.Errorf("cache: unknown driver %q", opts.Driver)
+	}
+}
@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should select the configured driver", func(t *testing.T) {
+		memory, err := New(Options{Driver: DriverMemory})
+		assert.NoError(t, err)
+		assert.IsType(t, &MemoryCache{}, memory)
+
+		redisCache, err := New(Options{Driver: DriverRedis, RedisAddr: "localhost:6379"})
+		assert.NoError(t, err)
+		assert.IsType(t, &RedisCache{}, redisCache)
+
+		distributed, err := New(Options{Driver: DriverMemory, Invalidation: true, RedisAddr: "localhost:6379"})
+		assert.NoError(t, err)
+		assert.IsType(t, &DistributedCache{}, distributed)
+	})
+
+	t.Run("should return an error for an unknown driver", func(t *testing.T) {
+		_, err := New(Options{Driver: "memcached"})
+
+		assert.EqualError(t, err, `cache: unknown driver "memcached"`)
+	})
+}
@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultInvalidationChannel = "location-api:cache-invalidation"
+	defaultFallbackTTL         = 5 * time.Second
+	healthCheckInterval        = 15 * time.Second
+	minReconnectDelay          = time.Second
+	maxReconnectDelay          = 30 * time.Second
+)
+
+// DistributedCache keeps values in a cache of this instance and tells every
+// other instance, over a Redis channel, to drop the keys it deletes. Run must be
+// running to hear about deletes elsewhere. Whenever the subscription is lost or
+// regained the local cache is flushed, since deletes may have been missed, and
+// until it is back values are kept for at most the fallback TTL.
+type DistributedCache struct {
+	local       Cache
+	client      *redis.Client
+	channel     string
+	origin      string
+	fallbackTTL time.Duration
+	subscribed  atomic.Bool
+}
+
+type invalidation struct {
+	Origin string `json:"origin"`
+	Key    string `json:"key"`
+	Prefix bool   `json:"prefix,omitempty"`
+}
+
+// NewDistributedCache returns a DistributedCache over local that publishes on
+// channel. An empty channel or a fallback TTL below one keeps the defaults.
+func NewDistributedCache(local Cache, client *redis.Client, channel string, fallbackTTL time.Duration) *DistributedCache {
+	if channel == "" {
+		channel = defaultInvalidationChannel
+	}
+
+	if fallbackTTL < 1 {
+		fallbackTTL = defaultFallbackTTL
+	}
+
+	return &DistributedCache{
+		local:       local,
+		client:      client,
+		channel:     channel,
+		origin:      newOrigin(),
+		fallbackTTL: fallbackTTL,
+	}
+}
+
+func (c *DistributedCache) Get(key string, dest interface{}) error {
+	return c.local.Get(key, dest)
+}
+
+func (c *DistributedCache) Set(key string, value interface{}, expiration time.Duration) error {
+	return c.local.Set(key, value, c.expiration(expiration))
+}
+
+// expiration caps expiration at the fallback TTL while deletes of other
+// instances may be missed.
+func (c *DistributedCache) expiration(expiration time.Duration) time.Duration {
+	if !c.subscribed.Load() && (expiration < 1 || expiration > c.fallbackTTL) {
+		return c.fallbackTTL
+	}
+
+	return expiration
+}
+
+func (c *DistributedCache) Delete(key string) error {
+	err := c.local.Delete(key)
+	c.publish(invalidation{Origin: c.origin, Key: key})
+
+	return err
+}
+
+func (c *DistributedCache) DeleteByPrefix(prefix string) error {
+	err := c.local.DeleteByPrefix(prefix)
+	c.publish(invalidation{Origin: c.origin, Key: prefix, Prefix: true})
+
+	return err
+}
+
+// beginLoad and endLoad use the guard of the local cache, which also sees the
+// deletes of other instances, as they are applied to it.
+func (c *DistributedCache) beginLoad(key string) uint64 {
+	if guard, ok := c.local.(loadGuard); ok {
+		return guard.beginLoad(key)
+	}
+
+	return 0
+}
+
+func (c *DistributedCache) endLoad(key string, epoch uint64, value interface{}, expiration time.Duration) error {
+	expiration = c.expiration(expiration)
+
+	if guard, ok := c.local.(loadGuard); ok {
+		return guard.endLoad(key, epoch, value, expiration)
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	return c.local.Set(key, value, expiration)
+}
+
+// Run subscribes to the invalidation channel and applies the deletes of other
+// instances until ctx is done, subscribing again with backoff when the
+// connection fails.
+func (c *DistributedCache) Run(ctx context.Context) {
+	delay := minReconnectDelay
+
+	for ctx.Err() == nil {
+		if c.listen(ctx) {
+			delay = minReconnectDelay
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Println("WARNING: cache invalidation subscription lost, retrying in", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
+
+// listen runs one subscription until it fails and reports whether it was ever
+// established.
+func (c *DistributedCache) listen(ctx context.Context) bool {
+	pubsub := c.client.Subscribe(ctx, c.channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		log.Println("ERROR: cache invalidation cannot subscribe:", err)
+		return false
+	}
+
+	c.setSubscribed(true)
+	defer c.setSubscribed(false)
+
+	log.Println("INFO: cache invalidation subscribed - Channel:", c.channel)
+
+	awaitingPong := false
+
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, healthCheckInterval)
+
+		var netErr net.Error
+
+		switch {
+		case err == nil:
+			awaitingPong = false
+		case errors.As(err, &netErr) && netErr.Timeout() && !awaitingPong:
+			// A quiet channel is normal; a ping that goes unanswered is not.
+			if err := pubsub.Ping(ctx); err != nil {
+				return true
+			}
+
+			awaitingPong = true
+
+			continue
+		default:
+			return true
+		}
+
+		if message, ok := msg.(*redis.Message); ok {
+			c.apply(message.Payload)
+		}
+	}
+}
+
+func (c *DistributedCache) setSubscribed(subscribed bool) {
+	c.subscribed.Store(subscribed)
+	_ = c.local.DeleteByPrefix("")
+}
+
+func (c *DistributedCache) apply(payload string) {
+	var event invalidation
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		log.Println("ERROR: cache invalidation cannot parse:", err)
+		return
+	}
+
+	if event.Origin == c.origin {
+		return
+	}
+
+	if event.Prefix {
+		_ = c.local.DeleteByPrefix(event.Key)
+	} else {
+		_ = c.local.Delete(event.Key)
+	}
+}
+
+func (c *DistributedCache) publish(event invalidation) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	if err := c.client.Publish(context.Background(), c.channel, payload).Err(); err != nil {
+		log.Println("ERROR: cache invalidation cannot publish - Key:", event.Key, "Error:", err)
+	}
+}
+
+func newOrigin() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
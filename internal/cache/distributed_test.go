@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDistributedCache(t *testing.T) (*DistributedCache, *MemoryCache, redismock.ClientMock) {
+	t.Helper()
+
+	db, mock := redismock.NewClientMock()
+	local := NewMemoryCache(0)
+
+	return NewDistributedCache(local, db, "", 0), local, mock
+}
+
+func invalidationPayload(t *testing.T, event invalidation) []byte {
+	t.Helper()
+
+	payload, err := json.Marshal(event)
+	assert.NoError(t, err)
+
+	return payload
+}
+
+func TestDistributedCache_Delete(t *testing.T) {
+	t.Run("should delete locally and publish the key", func(t *testing.T) {
+		c, local, mock := newTestDistributedCache(t)
+		c.subscribed.Store(true)
+
+		mock.ExpectPublish(defaultInvalidationChannel, invalidationPayload(t, invalidation{Origin: c.origin, Key: "test-key"})).SetVal(1)
+
+		assert.NoError(t, c.Set("test-key", 1, time.Minute))
+		assert.NoError(t, c.Delete("test-key"))
+
+		assert.Equal(t, 0, local.Len())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should delete locally and publish the prefix", func(t *testing.T) {
+		c, local, mock := newTestDistributedCache(t)
+		c.subscribed.Store(true)
+
+		mock.ExpectPublish(defaultInvalidationChannel, invalidationPayload(t, invalidation{Origin: c.origin, Key: "test-prefix", Prefix: true})).SetVal(1)
+
+		assert.NoError(t, c.Set("test-prefix:1", 1, time.Minute))
+		assert.NoError(t, c.Set("other", 2, time.Minute))
+		assert.NoError(t, c.DeleteByPrefix("test-prefix"))
+
+		assert.Equal(t, 1, local.Len())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should still delete locally when publishing fails", func(t *testing.T) {
+		c, local, mock := newTestDistributedCache(t)
+		c.subscribed.Store(true)
+
+		mock.ExpectPublish(defaultInvalidationChannel, invalidationPayload(t, invalidation{Origin: c.origin, Key: "test-key"})).
+			SetErr(errors.New("connection refused"))
+
+		assert.NoError(t, c.Set("test-key", 1, time.Minute))
+		assert.NoError(t, c.Delete("test-key"))
+
+		assert.Equal(t, 0, local.Len())
+	})
+}
+
+func TestDistributedCache_Set(t *testing.T) {
+	now := time.Date(2025, 3, 16, 10, 0, 0, 0, time.UTC)
+
+	t.Run("should keep the expiration while subscribed", func(t *testing.T) {
+		c, local, _ := newTestDistributedCache(t)
+		local.now = func() time.Time { return now }
+		c.subscribed.Store(true)
+
+		assert.NoError(t, c.Set("test-key", 1, time.Minute))
+
+		local.now = func() time.Time { return now.Add(30 * time.Second) }
+
+		var value int
+		assert.NoError(t, c.Get("test-key", &value))
+	})
+
+	t.Run("should cap the expiration at the fallback TTL while unsubscribed", func(t *testing.T) {
+		c, local, _ := newTestDistributedCache(t)
+		local.now = func() time.Time { return now }
+
+		assert.NoError(t, c.Set("test-key", 1, time.Minute))
+		assert.NoError(t, c.Set("forever", 1, 0))
+
+		local.now = func() time.Time { return now.Add(defaultFallbackTTL) }
+
+		var value int
+		assert.True(t, errors.Is(c.Get("test-key", &value), ErrMiss))
+		assert.True(t, errors.Is(c.Get("forever", &value), ErrMiss))
+	})
+}
+
+func TestDistributedCache_Apply(t *testing.T) {
+	t.Run("should apply deletes of other instances", func(t *testing.T) {
+		c, local, _ := newTestDistributedCache(t)
+		c.subscribed.Store(true)
+
+		assert.NoError(t, c.Set("test-prefix:1", 1, time.Minute))
+		assert.NoError(t, c.Set("test-key", 2, time.Minute))
+		assert.NoError(t, c.Set("other", 3, time.Minute))
+
+		c.apply(string(invalidationPayload(t, invalidation{Origin: "other-instance", Key: "test-prefix", Prefix: true})))
+		c.apply(string(invalidationPayload(t, invalidation{Origin: "other-instance", Key: "test-key"})))
+
+		var value int
+		assert.Equal(t, 1, local.Len())
+		assert.NoError(t, c.Get("other", &value))
+	})
+
+	t.Run("should ignore its own and unreadable events", func(t *testing.T) {
+		c, local, _ := newTestDistributedCache(t)
+		c.subscribed.Store(true)
+
+		assert.NoError(t, c.Set("test-key", 1, time.Minute))
+
+		c.apply(string(invalidationPayload(t, invalidation{Origin: c.origin, Key: "test-key"})))
+		c.apply("not json")
+
+		assert.Equal(t, 1, local.Len())
+	})
+
+	t.Run("should not let a load cache a key deleted by another instance", func(t *testing.T) {
+		c, local, _ := newTestDistributedCache(t)
+		c.subscribed.Store(true)
+
+		epoch := c.beginLoad("test-key")
+		c.apply(string(invalidationPayload(t, invalidation{Origin: "other-instance", Key: "test-key"})))
+
+		assert.NoError(t, c.endLoad("test-key", epoch, 1, time.Minute))
+		assert.Equal(t, 0, local.Len())
+	})
+}
+
+func TestDistributedCache_Run(t *testing.T) {
+	t.Run("should flush the local cache on losing the subscription", func(t *testing.T) {
+		c, local, _ := newTestDistributedCache(t)
+		c.subscribed.Store(true)
+
+		assert.NoError(t, c.Set("test-key", 1, time.Minute))
+
+		c.setSubscribed(false)
+
+		assert.Equal(t, 0, local.Len())
+	})
+
+	t.Run("should keep retrying until the context is done", func(t *testing.T) {
+		client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+		c := NewDistributedCache(NewMemoryCache(0), client, "", 0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		done := make(chan struct{})
+
+		go func() {
+			c.Run(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after the context was done")
+		}
+
+		assert.False(t, c.subscribed.Load())
+	})
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// loadGuard is implemented by caches that can tell whether a key was deleted
+// while a value for it was being loaded. The Loader uses it so that a load that
+// read the database before a write does not cache what it read after the write
+// has invalidated the key.
+type loadGuard interface {
+	// beginLoad registers a load of key and returns the epoch it started at.
+	beginLoad(key string) uint64
+	// endLoad finishes a load started at epoch, storing value under key only
+	// when key has not been deleted since. A nil value only finishes the load.
+	endLoad(key string, epoch uint64, value interface{}, expiration time.Duration) error
+}
+
+// epochs counts the deletes of keys that are being loaded, for caches that live
+// in this process. Only keys with a load in flight are tracked, so it stays as
+// small as the number of loads.
+type epochs struct {
+	mu      sync.Mutex
+	loading map[string]*keyEpoch
+}
+
+type keyEpoch struct {
+	loads int
+	epoch uint64
+}
+
+// beginLoad registers a load of key and returns its current epoch.
+func (e *epochs) beginLoad(key string) uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.loading == nil {
+		e.loading = make(map[string]*keyEpoch)
+	}
+
+	current, ok := e.loading[key]
+	if !ok {
+		current = &keyEpoch{}
+		e.loading[key] = current
+	}
+
+	current.loads++
+
+	return current.epoch
+}
+
+// finishLoad finishes a load started at epoch, calling set to store its value
+// only when key has not been deleted since. A nil set only finishes the load.
+// set runs under the lock, so a delete cannot slip in between the check and
+// the write.
+func (e *epochs) finishLoad(key string, epoch uint64, set func() error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	current := e.loading[key]
+
+	current.loads--
+	if current.loads == 0 {
+		delete(e.loading, key)
+	}
+
+	if set == nil || current.epoch != epoch {
+		return nil
+	}
+
+	return set()
+}
+
+// invalidate moves key to a new epoch. It has to be called before the key is
+// deleted, so that a load finishing in between is either deleted or not stored.
+func (e *epochs) invalidate(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if current, ok := e.loading[key]; ok {
+		current.epoch++
+	}
+}
+
+// invalidatePrefix moves every key being loaded that starts with prefix to a
+// new epoch.
+func (e *epochs) invalidatePrefix(prefix string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for key, current := range e.loading {
+		if strings.HasPrefix(key, prefix) {
+			current.epoch++
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	resultHit   = "hit"
+	resultStale = "stale"
+	resultMiss  = "miss"
+)
+
+var (
+	lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "location_api_cache_lookups_total",
+		Help: "Cache lookups by result: hit, stale (served while refreshing) or miss.",
+	}, []string{"cache", "result"})
+	coalesced = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "location_api_cache_coalesced_total",
+		Help: "Misses that waited for a load already in flight instead of starting one.",
+	}, []string{"cache"})
+	refreshDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "location_api_cache_refresh_duration_seconds",
+		Help:    "Time taken to load a value into the cache.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"cache"})
+	refreshErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "location_api_cache_refresh_errors_total",
+		Help: "Loads that failed, leaving the cached value as it was.",
+	}, []string{"cache"})
+)
+
+// Loader reads through a Cache. Concurrent misses of a key share one load, and
+// a value older than ttl is still served for staleTTL more while a single
+// background load replaces it.
+type Loader struct {
+	cache    Cache
+	name     string
+	ttl      time.Duration
+	staleTTL time.Duration
+	group    singleflight.Group
+	now      func() time.Time
+}
+
+// envelope is what the Loader stores, so it can tell fresh values from stale
+// ones without a second key.
+type envelope struct {
+	Data       json.RawMessage `json:"data"`
+	FreshUntil time.Time       `json:"fresh_until"`
+}
+
+// NewLoader returns a Loader over c. The name labels its metrics.
+func NewLoader(c Cache, name string, ttl, staleTTL time.Duration) *Loader {
+	return &Loader{
+		cache:    c,
+		name:     name,
+		ttl:      ttl,
+		staleTTL: staleTTL,
+		now:      time.Now,
+	}
+}
+
+// Load decodes the value cached under key into dest, calling load to fill the
+// cache when there is nothing usable. Only errors of load are returned; a cache
+// that cannot be read or written is treated as empty.
+func (l *Loader) Load(key string, dest interface{}, load func() (interface{}, error)) error {
+	var cached envelope
+	if err := l.cache.Get(key, &cached); err == nil && len(cached.Data) > 0 {
+		if l.now().Before(cached.FreshUntil) {
+			lookups.WithLabelValues(l.name, resultHit).Inc()
+		} else {
+			lookups.WithLabelValues(l.name, resultStale).Inc()
+
+			go l.refreshInBackground(key, load)
+		}
+
+		return json.Unmarshal(cached.Data, dest)
+	}
+
+	lookups.WithLabelValues(l.name, resultMiss).Inc()
+
+	loaded := false
+	data, err, _ := l.group.Do(key, func() (interface{}, error) {
+		loaded = true
+		return l.refresh(key, load)
+	})
+
+	if !loaded {
+		coalesced.WithLabelValues(l.name).Inc()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data.(json.RawMessage), dest)
+}
+
+func (l *Loader) refreshInBackground(key string, load func() (interface{}, error)) {
+	_, err, _ := l.group.Do(key, func() (interface{}, error) {
+		return l.refresh(key, load)
+	})
+	if err != nil {
+		log.Println("ERROR: cache refresh failed - Key:", key, "Error:", err)
+	}
+}
+
+// refresh loads the value of key and caches it, unless the key was deleted
+// while the load ran: the value may then predate the write that deleted it.
+// The callers waiting for this load still get the value.
+func (l *Loader) refresh(key string, load func() (interface{}, error)) (json.RawMessage, error) {
+	finish := l.beginLoad(key)
+
+	start := time.Now()
+	value, err := load()
+
+	refreshDuration.WithLabelValues(l.name).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		finish(nil)
+		refreshErrors.WithLabelValues(l.name).Inc()
+
+		return nil, err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		finish(nil)
+		return nil, err
+	}
+
+	finish(envelope{Data: data, FreshUntil: l.now().Add(l.ttl)})
+
+	return data, nil
+}
+
+// beginLoad captures the epoch of key before it is loaded and returns the
+// function that stores the loaded value if the epoch is still current, or only
+// ends the load when given nil. Caches without a loadGuard always store it.
+func (l *Loader) beginLoad(key string) func(value interface{}) {
+	expiration := l.ttl + l.staleTTL
+
+	guard, ok := l.cache.(loadGuard)
+	if !ok {
+		return func(value interface{}) {
+			if value != nil {
+				_ = l.cache.Set(key, value, expiration)
+			}
+		}
+	}
+
+	epoch := guard.beginLoad(key)
+
+	return func(value interface{}) {
+		_ = guard.endLoad(key, epoch, value, expiration)
+	}
+}
@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLoader(name string) (*Loader, *MemoryCache, *time.Time) {
+	c := NewMemoryCache(0)
+	l := NewLoader(c, name, time.Minute, time.Hour)
+	now := time.Date(2025, 3, 16, 10, 0, 0, 0, time.UTC)
+
+	c.now = func() time.Time { return now }
+	l.now = func() time.Time { return now }
+
+	return l, c, &now
+}
+
+func TestLoader_Load(t *testing.T) {
+	t.Run("should load once and then serve from the cache", func(t *testing.T) {
+		l, _, _ := newTestLoader("test-hit")
+		misses := testutil.ToFloat64(lookups.WithLabelValues("test-hit", resultMiss))
+		hits := testutil.ToFloat64(lookups.WithLabelValues("test-hit", resultHit))
+		calls := 0
+		load := func() (interface{}, error) {
+			calls++
+			return []string{"a", "b"}, nil
+		}
+
+		var first, second []string
+		assert.NoError(t, l.Load("key", &first, load))
+		assert.NoError(t, l.Load("key", &second, load))
+
+		assert.Equal(t, []string{"a", "b"}, first)
+		assert.Equal(t, first, second)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, misses+1, testutil.ToFloat64(lookups.WithLabelValues("test-hit", resultMiss)))
+		assert.Equal(t, hits+1, testutil.ToFloat64(lookups.WithLabelValues("test-hit", resultHit)))
+	})
+
+	t.Run("should serve a stale value while refreshing it in the background", func(t *testing.T) {
+		l, _, now := newTestLoader("test-stale")
+		stale := testutil.ToFloat64(lookups.WithLabelValues("test-stale", resultStale))
+		refreshed := make(chan struct{})
+		version := 0
+		load := func() (interface{}, error) {
+			version++
+			if version == 2 {
+				defer close(refreshed)
+			}
+
+			return version, nil
+		}
+
+		var value int
+		assert.NoError(t, l.Load("key", &value, load))
+
+		*now = now.Add(2 * time.Minute)
+
+		assert.NoError(t, l.Load("key", &value, load))
+		assert.Equal(t, 1, value)
+
+		select {
+		case <-refreshed:
+		case <-time.After(time.Second):
+			t.Fatal("stale value was not refreshed")
+		}
+
+		assert.Eventually(t, func() bool {
+			return l.Load("key", &value, load) == nil && value == 2
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, stale+1, testutil.ToFloat64(lookups.WithLabelValues("test-stale", resultStale)))
+	})
+
+	t.Run("should load again once the stale window has passed", func(t *testing.T) {
+		l, _, now := newTestLoader("test-expired")
+		version := 0
+		load := func() (interface{}, error) {
+			version++
+			return version, nil
+		}
+
+		var value int
+		assert.NoError(t, l.Load("key", &value, load))
+
+		*now = now.Add(2 * time.Hour)
+
+		assert.NoError(t, l.Load("key", &value, load))
+		assert.Equal(t, 2, value)
+	})
+
+	t.Run("should share one load between concurrent misses", func(t *testing.T) {
+		l, _, _ := newTestLoader("test-coalesced")
+		waitedBefore := testutil.ToFloat64(coalesced.WithLabelValues("test-coalesced"))
+		hitsBefore := testutil.ToFloat64(lookups.WithLabelValues("test-coalesced", resultHit))
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		var calls int32
+
+		load := func() (interface{}, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				close(started)
+			}
+			<-release
+
+			return "routes", nil
+		}
+
+		const callers = 10
+
+		var wg sync.WaitGroup
+
+		values := make([]string, callers)
+
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				if i > 0 {
+					<-started
+				}
+
+				assert.NoError(t, l.Load("key", &values[i], load))
+			}(i)
+		}
+
+		<-started
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		for _, value := range values {
+			assert.Equal(t, "routes", value)
+		}
+
+		waited := testutil.ToFloat64(coalesced.WithLabelValues("test-coalesced"))
+		hits := testutil.ToFloat64(lookups.WithLabelValues("test-coalesced", resultHit))
+		assert.Equal(t, float64(callers-1), waited-waitedBefore+hits-hitsBefore)
+	})
+
+	t.Run("should return the load error without caching anything", func(t *testing.T) {
+		l, c, _ := newTestLoader("test-error")
+		failures := testutil.ToFloat64(refreshErrors.WithLabelValues("test-error"))
+		expectedError := errors.New("db down")
+
+		var value int
+		err := l.Load("key", &value, func() (interface{}, error) {
+			return nil, expectedError
+		})
+
+		assert.Equal(t, expectedError, err)
+		assert.Equal(t, 0, c.Len())
+		assert.Equal(t, failures+1, testutil.ToFloat64(refreshErrors.WithLabelValues("test-error")))
+	})
+
+	t.Run("should not cache a miss that was deleted while it loaded", func(t *testing.T) {
+		l, c, _ := newTestLoader("test-invalidated-miss")
+		started := make(chan struct{})
+		release := make(chan struct{})
+		version := 0
+		load := func() (interface{}, error) {
+			version++
+			if version == 1 {
+				close(started)
+				<-release
+			}
+
+			return version, nil
+		}
+
+		done := make(chan int)
+
+		go func() {
+			var value int
+			assert.NoError(t, l.Load("key", &value, load))
+			done <- value
+		}()
+
+		<-started
+		assert.NoError(t, c.DeleteByPrefix("k"))
+		close(release)
+
+		assert.Equal(t, 1, <-done)
+		assert.Equal(t, 0, c.Len())
+
+		var value int
+		assert.NoError(t, l.Load("key", &value, load))
+		assert.Equal(t, 2, value)
+	})
+
+	t.Run("should not cache a refresh that was deleted while it loaded", func(t *testing.T) {
+		l, c, now := newTestLoader("test-invalidated-refresh")
+		started := make(chan struct{})
+		release := make(chan struct{})
+		version := 0
+		load := func() (interface{}, error) {
+			version++
+			if version == 2 {
+				close(started)
+				<-release
+			}
+
+			return version, nil
+		}
+
+		var value int
+		assert.NoError(t, l.Load("key", &value, load))
+
+		*now = now.Add(2 * time.Minute)
+
+		assert.NoError(t, l.Load("key", &value, load))
+		assert.Equal(t, 1, value)
+
+		<-started
+		assert.NoError(t, c.Delete("key"))
+		close(release)
+
+		assert.Never(t, func() bool { return c.Len() > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	})
+}
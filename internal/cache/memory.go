@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultMaxEntries = 10000
+
+// MemoryCache keeps values in process, evicting the least recently used entry
+// once it holds maxEntries. Values are stored as JSON, like in Redis, so callers
+// never share memory with what they cached.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	now        func() time.Time
+	epochs
+}
+
+type memoryEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+// NewMemoryCache returns an empty cache holding at most maxEntries values.
+// Values below one keep the default.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries < 1 {
+		maxEntries = defaultMaxEntries
+	}
+
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryCache) Set(key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	entry := &memoryEntry{key: key, data: data}
+	if expiration > 0 {
+		entry.expiresAt = c.now().Add(expiration)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) Get(key string, dest interface{}) error {
+	c.mu.Lock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return ErrMiss
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		c.mu.Unlock()
+
+		return ErrMiss
+	}
+
+	c.order.MoveToFront(element)
+	c.mu.Unlock()
+
+	return json.Unmarshal(entry.data, dest)
+}
+
+func (c *MemoryCache) Delete(key string) error {
+	c.invalidate(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) DeleteByPrefix(prefix string) error {
+	c.invalidatePrefix(prefix)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(element)
+		}
+	}
+
+	return nil
+}
+
+func (c *MemoryCache) endLoad(key string, epoch uint64, value interface{}, expiration time.Duration) error {
+	if value == nil {
+		return c.finishLoad(key, epoch, nil)
+	}
+
+	return c.finishLoad(key, epoch, func() error {
+		return c.Set(key, value, expiration)
+	})
+}
+
+// Len returns the number of entries held, including expired ones that have not
+// been read since they expired.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *MemoryCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCache_Get(t *testing.T) {
+	t.Run("should return a copy of the cached value", func(t *testing.T) {
+		c := NewMemoryCache(0)
+		value := map[string]string{"foo": "bar"}
+
+		assert.NoError(t, c.Set("test-key", value, time.Minute))
+
+		value["foo"] = "changed"
+
+		var actualValue map[string]string
+		assert.NoError(t, c.Get("test-key", &actualValue))
+		assert.Equal(t, map[string]string{"foo": "bar"}, actualValue)
+	})
+
+	t.Run("should miss an unknown key", func(t *testing.T) {
+		var actualValue map[string]string
+		err := NewMemoryCache(0).Get("non-existent", &actualValue)
+
+		assert.True(t, errors.Is(err, ErrMiss))
+	})
+
+	t.Run("should miss and drop an expired key", func(t *testing.T) {
+		c := NewMemoryCache(0)
+		now := time.Date(2025, 3, 16, 10, 0, 0, 0, time.UTC)
+		c.now = func() time.Time { return now }
+
+		assert.NoError(t, c.Set("short", 1, time.Second))
+		assert.NoError(t, c.Set("forever", 2, 0))
+
+		now = now.Add(time.Second)
+
+		var actualValue int
+		assert.True(t, errors.Is(c.Get("short", &actualValue), ErrMiss))
+		assert.NoError(t, c.Get("forever", &actualValue))
+		assert.Equal(t, 2, actualValue)
+		assert.Equal(t, 1, c.Len())
+	})
+}
+
+func TestMemoryCache_Set(t *testing.T) {
+	t.Run("should evict the least recently used key", func(t *testing.T) {
+		c := NewMemoryCache(2)
+
+		assert.NoError(t, c.Set("a", 1, 0))
+		assert.NoError(t, c.Set("b", 2, 0))
+
+		var actualValue int
+		assert.NoError(t, c.Get("a", &actualValue))
+
+		assert.NoError(t, c.Set("c", 3, 0))
+
+		assert.NoError(t, c.Get("a", &actualValue))
+		assert.True(t, errors.Is(c.Get("b", &actualValue), ErrMiss))
+		assert.NoError(t, c.Get("c", &actualValue))
+		assert.Equal(t, 2, c.Len())
+	})
+
+	t.Run("should replace the value of an existing key", func(t *testing.T) {
+		c := NewMemoryCache(2)
+
+		assert.NoError(t, c.Set("a", 1, 0))
+		assert.NoError(t, c.Set("a", 2, 0))
+
+		var actualValue int
+		assert.NoError(t, c.Get("a", &actualValue))
+		assert.Equal(t, 2, actualValue)
+		assert.Equal(t, 1, c.Len())
+	})
+
+	t.Run("should return an error when the value cannot be encoded", func(t *testing.T) {
+		c := NewMemoryCache(0)
+
+		assert.Error(t, c.Set("a", make(chan int), 0))
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("should be safe for concurrent use", func(t *testing.T) {
+		c := NewMemoryCache(10)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				var actualValue int
+
+				_ = c.Set(string(rune('a'+i%20)), i, time.Minute)
+				_ = c.Get(string(rune('a'+i%7)), &actualValue)
+				_ = c.DeleteByPrefix("b")
+			}(i)
+		}
+
+		wg.Wait()
+
+		assert.LessOrEqual(t, c.Len(), 10)
+	})
+}
+
+func TestMemoryCache_Delete(t *testing.T) {
+	c := NewMemoryCache(0)
+
+	assert.NoError(t, c.Set("test-key", 1, 0))
+	assert.NoError(t, c.Delete("test-key"))
+	assert.NoError(t, c.Delete("non-existent"))
+
+	var actualValue int
+	assert.True(t, errors.Is(c.Get("test-key", &actualValue), ErrMiss))
+}
+
+func TestMemoryCache_DeleteByPrefix(t *testing.T) {
+	c := NewMemoryCache(0)
+
+	assert.NoError(t, c.Set("test-prefix:1", 1, 0))
+	assert.NoError(t, c.Set("test-prefix:2", 2, 0))
+	assert.NoError(t, c.Set("other", 3, 0))
+
+	assert.NoError(t, c.DeleteByPrefix("test-prefix"))
+
+	var actualValue int
+	assert.True(t, errors.Is(c.Get("test-prefix:1", &actualValue), ErrMiss))
+	assert.True(t, errors.Is(c.Get("test-prefix:2", &actualValue), ErrMiss))
+	assert.NoError(t, c.Get("other", &actualValue))
+	assert.Equal(t, 3, actualValue)
+}
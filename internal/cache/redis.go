@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultRedisAddr = "redis:6379"
+
+// Deletes are counted in Redis, so that every instance sharing it sees them.
+// epochKey counts the deletes, a key deleted keeps the count it was deleted at
+// under epochKeyPrefix for epochMarkerTTL, longer than any load runs, and
+// prefixEpochsKey holds the count of the last delete of every prefix.
+const (
+	epochKey        = "location-api:cache-epoch"
+	epochKeyPrefix  = "location-api:cache-epoch:key:"
+	prefixEpochsKey = "location-api:cache-epoch:prefixes"
+	epochMarkerTTL  = 10 * time.Minute
+)
+
+var (
+	invalidateKeyScript = redis.NewScript(`
+local epoch = redis.call('INCR', KEYS[1])
+redis.call('SET', KEYS[2], epoch, 'PX', ARGV[1])
+return epoch
+`)
+	invalidatePrefixScript = redis.NewScript(`
+local epoch = redis.call('INCR', KEYS[1])
+redis.call('HSET', KEYS[2], ARGV[1], epoch)
+return epoch
+`)
+	// setIfCurrentScript stores a loaded value unless its key, or a prefix of
+	// it, was deleted after the epoch the load started at.
+	setIfCurrentScript = redis.NewScript(`
+local epoch = tonumber(ARGV[1])
+if tonumber(redis.call('GET', KEYS[2]) or '0') > epoch then
+	return 0
+end
+local prefixes = redis.call('HGETALL', KEYS[3])
+for i = 1, #prefixes, 2 do
+	if string.sub(KEYS[1], 1, #prefixes[i]) == prefixes[i] and tonumber(prefixes[i + 1]) > epoch then
+		return 0
+	end
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+)
+
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisClient connects to Redis at addr, or at the docker-compose service
+// when addr is empty.
+func NewRedisClient(addr, password string, db int) *redis.Client {
+	if addr == "" {
+		addr = defaultRedisAddr
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+}
+
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
+}
+
+func (c *RedisCache) Set(key string, value interface{}, expiration time.Duration) error {
+	ctx := context.Background()
+	data, err := json.Marshal(value)
+
+	if err != nil {
+		log.Println("ERROR: Json parsing error:", err)
+		return err
+	}
+
+	err = c.client.Set(ctx, key, data, expiration).Err()
+	if err != nil {
+		log.Println("ERROR: write redis error:", err)
+		return err
+	}
+
+	log.Println("INFO: write redis - Key:", key, "TTL:", expiration)
+
+	return nil
+}
+
+func (c *RedisCache) Get(key string, dest interface{}) error {
+	ctx := context.Background()
+	data, err := c.client.Get(ctx, key).Result()
+
+	if errors.Is(err, redis.Nil) {
+		log.Println("WARNING: key cannot found:", key)
+		return ErrMiss
+	} else if err != nil {
+		log.Println("ERROR: key cannot read:", err)
+		return err
+	}
+
+	err = json.Unmarshal([]byte(data), dest)
+	if err != nil {
+		log.Println("ERROR: Json parsing error:", err)
+		return err
+	}
+
+	log.Println("INFO: read from cache - Key:", key)
+
+	return nil
+}
+
+func (c *RedisCache) Delete(key string) error {
+	ctx := context.Background()
+
+	err := invalidateKeyScript.Run(ctx, c.client, []string{epochKey, epochKeyPrefix + key}, epochMarkerTTL.Milliseconds()).Err()
+	if err != nil {
+		log.Println("ERROR: cache cannot invalidate - Key:", key, "Error:", err)
+		return err
+	}
+
+	log.Println("DEBUG: delete from cache - Key:", key)
+
+	err = c.client.Del(ctx, key).Err()
+
+	if err != nil {
+		log.Println("ERROR: cache cannot delete - Key:", key, "Error:", err)
+	} else {
+		log.Println("INFO: cache delete properly - Key:", key)
+	}
+
+	return err
+}
+
+func (c *RedisCache) DeleteByPrefix(prefix string) error {
+	ctx := context.Background()
+
+	err := invalidatePrefixScript.Run(ctx, c.client, []string{epochKey, prefixEpochsKey}, prefix).Err()
+	if err != nil {
+		log.Println("ERROR: cache cannot invalidate - Prefix:", prefix, "Error:", err)
+		return err
+	}
+
+	log.Println("DEBUG: delete from cache - Prefix:", prefix)
+
+	var keys []string
+
+	iter := c.client.Scan(ctx, 0, prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+
+	if err := iter.Err(); err != nil {
+		log.Println("ERROR: cache cannot scan - Prefix:", prefix, "Error:", err)
+		return err
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err = c.client.Del(ctx, keys...).Err()
+
+	if err != nil {
+		log.Println("ERROR: cache cannot delete - Prefix:", prefix, "Error:", err)
+	} else {
+		log.Println("INFO: cache delete properly - Prefix:", prefix, "Count:", len(keys))
+	}
+
+	return err
+}
+
+// beginLoad returns the number of deletes so far. When it cannot be read the
+// load starts at zero, so that it is only stored if nothing was ever deleted.
+func (c *RedisCache) beginLoad(_ string) uint64 {
+	epoch, err := c.client.Get(context.Background(), epochKey).Uint64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Println("ERROR: cache epoch cannot read:", err)
+	}
+
+	return epoch
+}
+
+// endLoad compares the epochs and writes the value in one script, so a delete
+// from any instance cannot slip in between.
+func (c *RedisCache) endLoad(key string, epoch uint64, value interface{}, expiration time.Duration) error {
+	if value == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Println("ERROR: Json parsing error:", err)
+		return err
+	}
+
+	keys := []string{key, epochKeyPrefix + key, prefixEpochsKey}
+
+	stored, err := setIfCurrentScript.Run(context.Background(), c.client, keys, epoch, data, expiration.Milliseconds()).Int()
+	if err != nil {
+		log.Println("ERROR: write redis error:", err)
+		return err
+	}
+
+	if stored == 0 {
+		log.Println("INFO: skip write redis, deleted while loading - Key:", key)
+	} else {
+		log.Println("INFO: write redis - Key:", key, "TTL:", expiration)
+	}
+
+	return nil
+}
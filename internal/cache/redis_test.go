@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisCache_Get(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := NewRedisCache(db)
+
+	key := "test-key"
+	expectedValue := map[string]string{"foo": "bar"}
+	expectedJSON, _ := json.Marshal(expectedValue)
+
+	mock.ExpectGet(key).SetVal(string(expectedJSON))
+
+	var actualValue map[string]string
+	err := c.Get(key, &actualValue)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedValue, actualValue)
+	mock.ExpectationsWereMet()
+}
+
+func TestRedisCache_GetNotFound(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := NewRedisCache(db)
+
+	key := "non-existent"
+
+	mock.ExpectGet(key).RedisNil()
+
+	var actualValue map[string]string
+	err := c.Get(key, &actualValue)
+
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrMiss))
+	mock.ExpectationsWereMet()
+}
+
+func TestRedisCache_Delete(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := NewRedisCache(db)
+
+	key := "test-key"
+
+	mock.ExpectEvalSha(invalidateKeyScript.Hash(), []string{epochKey, epochKeyPrefix + key}, epochMarkerTTL.Milliseconds()).
+		SetVal(int64(1))
+	mock.ExpectDel(key).SetVal(1)
+
+	err := c.Delete(key)
+
+	assert.NoError(t, err)
+	mock.ExpectationsWereMet()
+}
+
+func TestRedisCache_DeleteByPrefix(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := NewRedisCache(db)
+
+	prefix := "test-prefix"
+
+	mock.ExpectEvalSha(invalidatePrefixScript.Hash(), []string{epochKey, prefixEpochsKey}, prefix).SetVal(int64(1))
+	mock.ExpectScan(0, prefix+"*", 0).SetVal([]string{"test-prefix:1", "test-prefix:2"}, 0)
+	mock.ExpectDel("test-prefix:1", "test-prefix:2").SetVal(2)
+
+	err := c.DeleteByPrefix(prefix)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisCache_LoadGuard(t *testing.T) {
+	key := "test-key"
+	keys := []string{key, epochKeyPrefix + key, prefixEpochsKey}
+	value := map[string]string{"foo": "bar"}
+	data, _ := json.Marshal(value)
+
+	t.Run("should start a load at the shared delete count", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		c := NewRedisCache(db)
+
+		assert.Implements(t, (*loadGuard)(nil), c)
+
+		mock.ExpectGet(epochKey).SetVal("4")
+		assert.Equal(t, uint64(4), c.beginLoad(key))
+
+		mock.ExpectGet(epochKey).RedisNil()
+		assert.Equal(t, uint64(0), c.beginLoad(key))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should store the value through the epoch check", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		c := NewRedisCache(db)
+
+		mock.ExpectEvalSha(setIfCurrentScript.Hash(), keys, uint64(4), data, time.Minute.Milliseconds()).SetVal(int64(1))
+
+		assert.NoError(t, c.endLoad(key, 4, value, time.Minute))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should not fail when another instance deleted the key", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		c := NewRedisCache(db)
+
+		mock.ExpectEvalSha(setIfCurrentScript.Hash(), keys, uint64(4), data, time.Minute.Milliseconds()).SetVal(int64(0))
+
+		assert.NoError(t, c.endLoad(key, 4, value, time.Minute))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should only finish a load without a value", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		c := NewRedisCache(db)
+
+		assert.NoError(t, c.endLoad(key, 4, nil, time.Minute))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"location-api/model"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var csvColumns = []string{"name", "latitude", "longitude", "marker_color"}
+
+// maxCSVRows matches the limit of CreateLocationsRequest, which imports go
+// through.
+const maxCSVRows = 10000
+
+// csvImport is what readLocationsCSV got out of a file. Ignored lists the
+// header names that are not location columns, so that a misspelt column is
+// reported rather than silently dropped.
+type csvImport struct {
+	Rows    []csvLocation
+	Failed  []model.ImportLocationError
+	Ignored []string
+}
+
+// csvLocation is a location read from an import file along with the line it
+// started on, so that errors can point back at the spreadsheet row.
+type csvLocation struct {
+	Line     int
+	Location model.CreateLocationRequest
+}
+
+// readLocationsCSV reads locations from a CSV file whose first row names the
+// columns. Columns are matched by name in any order and unknown ones are
+// ignored but listed. Rows that cannot be parsed are returned as per-line
+// errors; a missing header or required column, or more than maxCSVRows rows,
+// fails the whole file with model.ErrInvalidCSV.
+func readLocationsCSV(r io.Reader) (*csvImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: missing header row", model.ErrInvalidCSV)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", model.ErrInvalidCSV, err.Error())
+	}
+
+	columns := make(map[string]int, len(header))
+	res := &csvImport{Rows: []csvLocation{}, Failed: []model.ImportLocationError{}}
+
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		column := strings.ToLower(name)
+
+		if !slices.Contains(csvColumns, column) {
+			if name != "" {
+				res.Ignored = append(res.Ignored, name)
+			}
+
+			continue
+		}
+
+		if _, ok := columns[column]; !ok {
+			columns[column] = i
+		}
+	}
+
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", model.ErrInvalidCSV, name)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if len(res.Rows)+len(res.Failed) == maxCSVRows {
+			return nil, fmt.Errorf("%w: more than %d rows", model.ErrInvalidCSV, maxCSVRows)
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			res.Failed = append(res.Failed, model.ImportLocationError{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		location, err := parseCSVLocation(record, columns)
+		if err != nil {
+			res.Failed = append(res.Failed, model.ImportLocationError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		res.Rows = append(res.Rows, csvLocation{Line: line, Location: location})
+	}
+
+	return res, nil
+}
+
+func parseCSVLocation(record []string, columns map[string]int) (model.CreateLocationRequest, error) {
+	field := func(name string) string {
+		if i := columns[name]; i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+
+		return ""
+	}
+
+	latitude, err := strconv.ParseFloat(field("latitude"), 64)
+	if err != nil {
+		return model.CreateLocationRequest{}, fmt.Errorf("latitude %q is not a number", field("latitude"))
+	}
+
+	longitude, err := strconv.ParseFloat(field("longitude"), 64)
+	if err != nil {
+		return model.CreateLocationRequest{}, fmt.Errorf("longitude %q is not a number", field("longitude"))
+	}
+
+	return model.CreateLocationRequest{
+		Name:        field("name"),
+		Latitude:    &latitude,
+		Longitude:   &longitude,
+		MarkerColor: field("marker_color"),
+	}, nil
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"location-api/model"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLocationsCSV(t *testing.T) {
+	t.Run("should match columns by name and ignore extra ones", func(t *testing.T) {
+		data := "\ufeffNotes,Marker_Color,name,longitude,latitude\n" +
+			"head office,FFFFFF,first,32.5,40.5\n" +
+			"\n" +
+			",000000,second,35,45\n"
+
+		file, err := readLocationsCSV(strings.NewReader(data))
+		assert.Nil(t, err)
+		assert.Empty(t, file.Failed)
+		assert.Equal(t, []string{"Notes"}, file.Ignored)
+		assert.Equal(t, []csvLocation{
+			{Line: 2, Location: model.CreateLocationRequest{Name: "first", Latitude: float64Ptr(40.5), Longitude: float64Ptr(32.5), MarkerColor: "FFFFFF"}},
+			{Line: 4, Location: model.CreateLocationRequest{Name: "second", Latitude: float64Ptr(45), Longitude: float64Ptr(35), MarkerColor: "000000"}},
+		}, file.Rows)
+	})
+
+	t.Run("should report rows that cannot be parsed by line", func(t *testing.T) {
+		data := "name,latitude,longitude,marker_color\n" +
+			"first,north,32.5,FFFFFF\n" +
+			"second,45\n" +
+			"third,40.5,32.5,FFFFFF\n"
+
+		file, err := readLocationsCSV(strings.NewReader(data))
+		assert.Nil(t, err)
+		assert.Len(t, file.Rows, 1)
+		assert.Equal(t, 4, file.Rows[0].Line)
+		assert.Equal(t, []model.ImportLocationError{
+			{Line: 2, Error: `latitude "north" is not a number`},
+			{Line: 3, Error: `longitude "" is not a number`},
+		}, file.Failed)
+	})
+
+	t.Run("should reject a file without a required column", func(t *testing.T) {
+		_, err := readLocationsCSV(strings.NewReader("name,latitude,longitude\nfirst,40.5,32.5\n"))
+		assert.ErrorIs(t, err, model.ErrInvalidCSV)
+
+		_, err = readLocationsCSV(strings.NewReader("name,lattitude,longitude,marker_color\nfirst,40.5,32.5,FFFFFF\n"))
+		assert.ErrorIs(t, err, model.ErrInvalidCSV)
+
+		_, err = readLocationsCSV(strings.NewReader(""))
+		assert.ErrorIs(t, err, model.ErrInvalidCSV)
+	})
+
+	t.Run("should reject a file with more rows than a bulk create", func(t *testing.T) {
+		data := "name,latitude,longitude,marker_color\n" + strings.Repeat("first,40.5,32.5,FFFFFF\n", maxCSVRows)
+
+		file, err := readLocationsCSV(strings.NewReader(data))
+		assert.Nil(t, err)
+		assert.Len(t, file.Rows, maxCSVRows)
+
+		_, err = readLocationsCSV(strings.NewReader(data + "second,40.5,32.5,FFFFFF\n"))
+		assert.ErrorIs(t, err, model.ErrInvalidCSV)
+	})
+}
@@ -0,0 +1,96 @@
+package encoder
+
+import (
+	"encoding/csv"
+	"io"
+	"location-api/model"
+	"strconv"
+)
+
+func init() {
+	Register("csv", CSV{})
+}
+
+// CSV writes one row per location with the columns ImportLocations reads back,
+// led by the id. Routes add the distance from the route origin.
+type CSV struct{}
+
+func (CSV) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (CSV) FileExtension() string {
+	return "csv"
+}
+
+func (CSV) EncodeLocations(w io.Writer, stream LocationStream) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"id", "name", "latitude", "longitude", "marker_color"}); err != nil {
+		return err
+	}
+
+	rows := 0
+
+	err := stream(func(location *model.GetLocationResponse) error {
+		err := writer.Write([]string{
+			location.ID,
+			location.Name,
+			formatFloat(location.Latitude),
+			formatFloat(location.Longitude),
+			location.MarkerColor,
+		})
+		if err != nil {
+			return err
+		}
+
+		rows++
+		if rows%flushEvery == 0 {
+			return flushCSV(writer, w)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return flushCSV(writer, w)
+}
+
+func (CSV) EncodeRoutes(w io.Writer, res *model.GetRoutesResponse) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"id", "name", "latitude", "longitude", "marker_color", "distance"}); err != nil {
+		return err
+	}
+
+	for _, route := range res.Routes {
+		err := writer.Write([]string{
+			route.ID,
+			route.Name,
+			formatFloat(route.Latitude),
+			formatFloat(route.Longitude),
+			route.MarkerColor,
+			formatFloat(route.Distance),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return flushCSV(writer, w)
+}
+
+func flushCSV(writer *csv.Writer, w io.Writer) error {
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	return flush(w)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
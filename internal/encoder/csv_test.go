@@ -0,0 +1,37 @@
+package encoder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSV_EncodeLocations(t *testing.T) {
+	t.Run("should write a header and one row per location", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := CSV{}.EncodeLocations(&buf, streamOf(testLocation))
+		assert.Nil(t, err)
+		assert.Equal(t,
+			"id,name,latitude,longitude,marker_color\n"+
+				"67d562e3d9f2d225ca4d9918,\"head office, east\",40.5,32.25,FF8000\n",
+			buf.String(),
+		)
+	})
+}
+
+func TestCSV_EncodeRoutes(t *testing.T) {
+	t.Run("should write the stops in order with their distance", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := CSV{}.EncodeRoutes(&buf, &testRoutes)
+		assert.Nil(t, err)
+		assert.Equal(t,
+			"id,name,latitude,longitude,marker_color,distance\n"+
+				"67d562e3d9f2d225ca4d9918,first,40.5,32.25,FF8000,1.5\n"+
+				"67d562e3d9f2d225ca4d9919,second,41,33,000000,2.5\n",
+			buf.String(),
+		)
+	})
+}
@@ -0,0 +1,64 @@
+// Package encoder writes locations and routes in the file formats offered next
+// to the JSON responses. Each format registers itself under the name clients
+// pass as the format query parameter.
+package encoder
+
+import (
+	"io"
+	"location-api/model"
+	"sort"
+	"strings"
+)
+
+const flushEvery = 500
+
+// LocationStream calls fn for every location to encode, in order, and stops at
+// the first error fn returns.
+type LocationStream func(fn func(location *model.GetLocationResponse) error) error
+
+type Encoder interface {
+	// ContentType is sent as the Content-Type of the response.
+	ContentType() string
+	// FileExtension names the download, without the leading dot.
+	FileExtension() string
+	// EncodeLocations writes every location of stream to w as it arrives.
+	EncodeLocations(w io.Writer, stream LocationStream) error
+	// EncodeRoutes writes the stops of res to w in visiting order.
+	EncodeRoutes(w io.Writer, res *model.GetRoutesResponse) error
+}
+
+var encoders = map[string]Encoder{}
+
+// Register makes enc available under format. It is meant to be called from init
+// and replaces any encoder already registered under that name.
+func Register(format string, enc Encoder) {
+	encoders[strings.ToLower(format)] = enc
+}
+
+// Lookup returns the encoder registered under format, ignoring case.
+func Lookup(format string) (Encoder, bool) {
+	enc, ok := encoders[strings.ToLower(format)]
+	return enc, ok
+}
+
+// Formats returns the registered format names in alphabetical order.
+func Formats() []string {
+	formats := make([]string, 0, len(encoders))
+	for format := range encoders {
+		formats = append(formats, format)
+	}
+
+	sort.Strings(formats)
+
+	return formats
+}
+
+// flush pushes buffered output to the client when w supports it, so that long
+// exports are sent in chunks instead of all at the end.
+func flush(w io.Writer) error {
+	if flusher, ok := w.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package encoder
+
+import (
+	"location-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testLocation = model.GetLocationResponse{
+	ID:          "67d562e3d9f2d225ca4d9918",
+	Name:        "head office, east",
+	Latitude:    40.5,
+	Longitude:   32.25,
+	MarkerColor: "FF8000",
+}
+
+var testRoutes = model.GetRoutesResponse{
+	Routes: []model.Route{
+		{ID: "67d562e3d9f2d225ca4d9918", Name: "first", Latitude: 40.5, Longitude: 32.25, Distance: 1.5, MarkerColor: "FF8000"},
+		{ID: "67d562e3d9f2d225ca4d9919", Name: "second", Latitude: 41, Longitude: 33, Distance: 2.5, MarkerColor: "000000"},
+	},
+}
+
+var tourStart = model.Coordinate{Latitude: float64Ptr(40), Longitude: float64Ptr(32)}
+
+// testTour goes back to its start and could not find one of its stops.
+var testTour = model.GetRoutesResponse{
+	Routes:     testRoutes.Routes,
+	Start:      &tourStart,
+	End:        &tourStart,
+	MissingIDs: []string{"67d562e3d9f2d225ca4d9920"},
+}
+
+func streamOf(locations ...model.GetLocationResponse) LocationStream {
+	return func(fn func(location *model.GetLocationResponse) error) error {
+		for i := range locations {
+			if err := fn(&locations[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Run("should find registered formats ignoring case", func(t *testing.T) {
+		enc, ok := Lookup("KML")
+
+		assert.True(t, ok)
+		assert.Equal(t, KML{}, enc)
+		assert.Equal(t, []string{"csv", "gpx", "kml"}, Formats())
+	})
+
+	t.Run("should not find unknown formats", func(t *testing.T) {
+		_, ok := Lookup("xlsx")
+
+		assert.False(t, ok)
+	})
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,139 @@
+package encoder
+
+import (
+	"encoding/xml"
+	"io"
+	"location-api/model"
+)
+
+const gpxNamespace = "http://www.topografix.com/GPX/1/1"
+
+// gpxExtensionNamespace is the namespace of the elements this API adds in
+// <extensions>, which GPX requires to be outside its own. They are written with
+// the prefix declared on <gpx>.
+const gpxExtensionNamespace = "urn:location-api"
+
+func init() {
+	Register("gpx", GPX{})
+}
+
+// GPX writes locations as waypoints for GPS units. Routes become a single <rte>
+// whose <rtept>s keep the visiting order, from the start of a tour to where it
+// ends, and whose <extensions> list the IDs that were not found.
+type GPX struct{}
+
+type gpxPoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+	Name      string  `xml:"name"`
+}
+
+type gpxRoute struct {
+	XMLName    xml.Name       `xml:"rte"`
+	Name       string         `xml:"name"`
+	Extensions *gpxExtensions `xml:"extensions,omitempty"`
+	Points     []gpxPoint     `xml:"rtept"`
+}
+
+type gpxExtensions struct {
+	MissingIDs []string `xml:"locationapi:missing_id"`
+}
+
+func (GPX) ContentType() string {
+	return "application/gpx+xml"
+}
+
+func (GPX) FileExtension() string {
+	return "gpx"
+}
+
+func (GPX) EncodeLocations(w io.Writer, stream LocationStream) error {
+	enc, err := startGPX(w)
+	if err != nil {
+		return err
+	}
+
+	waypoint := xml.StartElement{Name: xml.Name{Local: "wpt"}}
+	rows := 0
+
+	err = stream(func(location *model.GetLocationResponse) error {
+		point := gpxPoint{Latitude: location.Latitude, Longitude: location.Longitude, Name: location.Name}
+		if err := enc.EncodeElement(point, waypoint); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%flushEvery == 0 {
+			return flushXML(enc, w)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return endGPX(enc, w)
+}
+
+func (GPX) EncodeRoutes(w io.Writer, res *model.GetRoutesResponse) error {
+	enc, err := startGPX(w)
+	if err != nil {
+		return err
+	}
+
+	route := gpxRoute{Name: "Route", Points: make([]gpxPoint, 0, len(res.Routes)+2)}
+
+	if len(res.MissingIDs) > 0 {
+		route.Extensions = &gpxExtensions{MissingIDs: res.MissingIDs}
+	}
+
+	if res.Start != nil {
+		route.Points = append(route.Points, gpxPoint{Latitude: *res.Start.Latitude, Longitude: *res.Start.Longitude, Name: "Start"})
+	}
+
+	for _, stop := range res.Routes {
+		route.Points = append(route.Points, gpxPoint{Latitude: stop.Latitude, Longitude: stop.Longitude, Name: stop.Name})
+	}
+
+	if res.End != nil {
+		route.Points = append(route.Points, gpxPoint{Latitude: *res.End.Latitude, Longitude: *res.End.Longitude, Name: "End"})
+	}
+
+	if err := enc.Encode(route); err != nil {
+		return err
+	}
+
+	return endGPX(enc, w)
+}
+
+func startGPX(w io.Writer) (*xml.Encoder, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+
+	enc := xml.NewEncoder(w)
+
+	err := enc.EncodeToken(xml.StartElement{
+		Name: xml.Name{Local: "gpx"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "version"}, Value: "1.1"},
+			{Name: xml.Name{Local: "creator"}, Value: "location-api"},
+			{Name: xml.Name{Local: "xmlns"}, Value: gpxNamespace},
+			{Name: xml.Name{Local: "xmlns:locationapi"}, Value: gpxExtensionNamespace},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return enc, nil
+}
+
+func endGPX(enc *xml.Encoder, w io.Writer) error {
+	if err := enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "gpx"}}); err != nil {
+		return err
+	}
+
+	return flushXML(enc, w)
+}
@@ -0,0 +1,70 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGPX_EncodeLocations(t *testing.T) {
+	t.Run("should write one waypoint per location", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := GPX{}.EncodeLocations(&buf, streamOf(testLocation))
+		assert.Nil(t, err)
+		assert.Equal(t,
+			`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+				`<gpx version="1.1" creator="location-api" xmlns="http://www.topografix.com/GPX/1/1" xmlns:locationapi="urn:location-api">`+
+				`<wpt lat="40.5" lon="32.25"><name>head office, east</name></wpt></gpx>`,
+			buf.String(),
+		)
+	})
+}
+
+func TestGPX_EncodeRoutes(t *testing.T) {
+	t.Run("should write a route with its points in order", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := GPX{}.EncodeRoutes(&buf, &testRoutes)
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(),
+			`<rte><name>Route</name>`+
+				`<rtept lat="40.5" lon="32.25"><name>first</name></rtept>`+
+				`<rtept lat="41" lon="33"><name>second</name></rtept></rte></gpx>`,
+		)
+	})
+
+	t.Run("should go from the start back to it and list missing ids", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := GPX{}.EncodeRoutes(&buf, &testTour)
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(),
+			`<rte><name>Route</name>`+
+				`<extensions><locationapi:missing_id>67d562e3d9f2d225ca4d9920</locationapi:missing_id></extensions>`+
+				`<rtept lat="40" lon="32"><name>Start</name></rtept>`+
+				`<rtept lat="40.5" lon="32.25"><name>first</name></rtept>`+
+				`<rtept lat="41" lon="33"><name>second</name></rtept>`+
+				`<rtept lat="40" lon="32"><name>End</name></rtept></rte></gpx>`,
+		)
+	})
+
+	t.Run("should keep the extensions out of the GPX namespace", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		assert.Nil(t, GPX{}.EncodeRoutes(&buf, &testTour))
+
+		var gpx struct {
+			Route struct {
+				MissingIDs []string `xml:"urn:location-api extensions>missing_id"`
+				Foreign    []string `xml:"http://www.topografix.com/GPX/1/1 extensions>missing_id"`
+			} `xml:"rte"`
+		}
+
+		assert.Nil(t, xml.Unmarshal(buf.Bytes(), &gpx))
+		assert.Equal(t, testTour.MissingIDs, gpx.Route.MissingIDs)
+		assert.Empty(t, gpx.Route.Foreign)
+	})
+}
@@ -0,0 +1,205 @@
+package encoder
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"location-api/model"
+	"strings"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+func init() {
+	Register("kml", KML{})
+}
+
+// KML writes locations as Placemarks for Google Earth. marker_color becomes the
+// icon colour of each Placemark, and routes also get a LineString through the
+// stops in visiting order, from the start of a tour to where it ends. IDs that
+// were not found are listed in the description of the Document.
+type KML struct{}
+
+type kmlPlacemark struct {
+	XMLName      xml.Name        `xml:"Placemark"`
+	Name         string          `xml:"name"`
+	Style        *kmlStyle       `xml:"Style,omitempty"`
+	ExtendedData *kmlData        `xml:"ExtendedData,omitempty"`
+	Point        *kmlCoordinates `xml:"Point,omitempty"`
+	LineString   *kmlCoordinates `xml:"LineString,omitempty"`
+}
+
+type kmlStyle struct {
+	Color string `xml:"IconStyle>color"`
+}
+
+type kmlData struct {
+	Data []kmlDataValue `xml:"Data"`
+}
+
+type kmlDataValue struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlCoordinates struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+func (KML) ContentType() string {
+	return "application/vnd.google-earth.kml+xml"
+}
+
+func (KML) FileExtension() string {
+	return "kml"
+}
+
+func (KML) EncodeLocations(w io.Writer, stream LocationStream) error {
+	enc, err := startKML(w, "Locations")
+	if err != nil {
+		return err
+	}
+
+	rows := 0
+
+	err = stream(func(location *model.GetLocationResponse) error {
+		placemark := kmlStop(location.ID, location.Name, location.Latitude, location.Longitude, location.MarkerColor)
+		if err := enc.Encode(placemark); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%flushEvery == 0 {
+			return flushXML(enc, w)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return endKML(enc, w)
+}
+
+func (KML) EncodeRoutes(w io.Writer, res *model.GetRoutesResponse) error {
+	enc, err := startKML(w, "Route")
+	if err != nil {
+		return err
+	}
+
+	if len(res.MissingIDs) > 0 {
+		description := "Missing IDs: " + strings.Join(res.MissingIDs, ", ")
+		if err := enc.EncodeElement(description, xml.StartElement{Name: xml.Name{Local: "description"}}); err != nil {
+			return err
+		}
+	}
+
+	coordinates := make([]string, 0, len(res.Routes)+2)
+
+	if res.Start != nil {
+		coordinates = append(coordinates, kmlPosition(*res.Start.Latitude, *res.Start.Longitude))
+	}
+
+	for _, route := range res.Routes {
+		placemark := kmlStop(route.ID, route.Name, route.Latitude, route.Longitude, route.MarkerColor)
+		placemark.ExtendedData.Data = append(placemark.ExtendedData.Data, kmlDataValue{
+			Name:  "distance",
+			Value: formatFloat(route.Distance),
+		})
+
+		if err := enc.Encode(placemark); err != nil {
+			return err
+		}
+
+		coordinates = append(coordinates, kmlPosition(route.Latitude, route.Longitude))
+	}
+
+	if res.End != nil {
+		coordinates = append(coordinates, kmlPosition(*res.End.Latitude, *res.End.Longitude))
+	}
+
+	if len(coordinates) > 1 {
+		err := enc.Encode(kmlPlacemark{
+			Name:       "Route",
+			LineString: &kmlCoordinates{Coordinates: strings.Join(coordinates, " ")},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return endKML(enc, w)
+}
+
+func kmlStop(id, name string, latitude, longitude float64, markerColor string) kmlPlacemark {
+	placemark := kmlPlacemark{
+		Name:         name,
+		ExtendedData: &kmlData{Data: []kmlDataValue{{Name: "id", Value: id}}},
+		Point:        &kmlCoordinates{Coordinates: kmlPosition(latitude, longitude)},
+	}
+
+	if color, ok := kmlColor(markerColor); ok {
+		placemark.Style = &kmlStyle{Color: color}
+	}
+
+	return placemark
+}
+
+func kmlPosition(latitude, longitude float64) string {
+	return formatFloat(longitude) + "," + formatFloat(latitude)
+}
+
+// kmlColor converts an RRGGBB marker colour into KML's opaque aabbggrr form.
+func kmlColor(markerColor string) (string, bool) {
+	rgb, err := hex.DecodeString(markerColor)
+	if err != nil || len(rgb) != 3 {
+		return "", false
+	}
+
+	return hex.EncodeToString([]byte{0xff, rgb[2], rgb[1], rgb[0]}), true
+}
+
+func startKML(w io.Writer, name string) (*xml.Encoder, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+
+	enc := xml.NewEncoder(w)
+
+	kml := xml.StartElement{
+		Name: xml.Name{Local: "kml"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: kmlNamespace}},
+	}
+	document := xml.StartElement{Name: xml.Name{Local: "Document"}}
+
+	for _, token := range []xml.Token{kml, document} {
+		if err := enc.EncodeToken(token); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.EncodeElement(name, xml.StartElement{Name: xml.Name{Local: "name"}}); err != nil {
+		return nil, err
+	}
+
+	return enc, nil
+}
+
+func endKML(enc *xml.Encoder, w io.Writer) error {
+	for _, name := range []string{"Document", "kml"} {
+		if err := enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+
+	return flushXML(enc, w)
+}
+
+func flushXML(enc *xml.Encoder, w io.Writer) error {
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	return flush(w)
+}
@@ -0,0 +1,60 @@
+package encoder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKML_EncodeLocations(t *testing.T) {
+	t.Run("should write a placemark styled by marker color", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := KML{}.EncodeLocations(&buf, streamOf(testLocation))
+		assert.Nil(t, err)
+		assert.Equal(t,
+			`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+				`<kml xmlns="http://www.opengis.net/kml/2.2"><Document><name>Locations</name>`+
+				`<Placemark><name>head office, east</name><Style><IconStyle><color>ff0080ff</color></IconStyle></Style>`+
+				`<ExtendedData><Data name="id"><value>67d562e3d9f2d225ca4d9918</value></Data></ExtendedData>`+
+				`<Point><coordinates>32.25,40.5</coordinates></Point></Placemark>`+
+				`</Document></kml>`,
+			buf.String(),
+		)
+	})
+}
+
+func TestKML_EncodeRoutes(t *testing.T) {
+	t.Run("should end with a line through the stops in order", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := KML{}.EncodeRoutes(&buf, &testRoutes)
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(), `<Data name="distance"><value>2.5</value></Data>`)
+		assert.Contains(t, buf.String(),
+			`<Placemark><name>Route</name><LineString><coordinates>32.25,40.5 33,41</coordinates></LineString></Placemark>`+
+				`</Document></kml>`,
+		)
+	})
+
+	t.Run("should go from the start back to it and list missing ids", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		err := KML{}.EncodeRoutes(&buf, &testTour)
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(), `<name>Route</name><description>Missing IDs: 67d562e3d9f2d225ca4d9920</description>`)
+		assert.Contains(t, buf.String(),
+			`<LineString><coordinates>32,40 32.25,40.5 33,41 32,40</coordinates></LineString>`,
+		)
+	})
+}
+
+func TestKMLColor(t *testing.T) {
+	color, ok := kmlColor("FF8000")
+	assert.True(t, ok)
+	assert.Equal(t, "ff0080ff", color)
+
+	_, ok = kmlColor("red")
+	assert.False(t, ok)
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"location-api/model"
+)
+
+const (
+	geoJSONFeatureCollection = "FeatureCollection"
+	geoJSONFeature           = "Feature"
+	geoJSONPoint             = "Point"
+	geoJSONLineString        = "LineString"
+)
+
+var (
+	errFeatureNotPoint    = errors.New("geometry must be a Point")
+	errFeatureCoordinates = errors.New("point coordinates must be [longitude, latitude]")
+)
+
+// locationsFeatureCollection turns locations into Point features with name and
+// marker_color as properties.
+func locationsFeatureCollection(locations []model.GetLocationResponse) model.GeoJSONFeatureCollection {
+	features := make([]model.GeoJSONFeature, 0, len(locations))
+
+	for _, location := range locations {
+		features = append(features, pointFeature(location.ID, location.Latitude, location.Longitude, map[string]interface{}{
+			"name":         location.Name,
+			"marker_color": location.MarkerColor,
+		}))
+	}
+
+	return model.GeoJSONFeatureCollection{Type: geoJSONFeatureCollection, Features: features}
+}
+
+// routesFeatureCollection turns route stops into Point features that also carry
+// their distances. With withLine a LineString through the stops in visiting
+// order is appended as the last feature.
+func routesFeatureCollection(res *model.GetRoutesResponse, withLine bool) model.GeoJSONFeatureCollection {
+	features := make([]model.GeoJSONFeature, 0, len(res.Routes)+1)
+	line := make([][]float64, 0, len(res.Routes))
+
+	for _, route := range res.Routes {
+		properties := map[string]interface{}{
+			"name":         route.Name,
+			"marker_color": route.MarkerColor,
+			"distance":     route.Distance,
+		}
+
+		if route.LegDistance != 0 || route.CumulativeDistance != 0 {
+			properties["leg_distance"] = route.LegDistance
+			properties["cumulative_distance"] = route.CumulativeDistance
+		}
+
+		features = append(features, pointFeature(route.ID, route.Latitude, route.Longitude, properties))
+		line = append(line, []float64{route.Longitude, route.Latitude})
+	}
+
+	if withLine && len(line) > 1 {
+		properties := map[string]interface{}{}
+		if res.TotalDistance != 0 {
+			properties["total_distance"] = res.TotalDistance
+		}
+
+		features = append(features, model.GeoJSONFeature{
+			Type:       geoJSONFeature,
+			Geometry:   model.GeoJSONGeometry{Type: geoJSONLineString, Coordinates: line},
+			Properties: properties,
+		})
+	}
+
+	return model.GeoJSONFeatureCollection{Type: geoJSONFeatureCollection, Features: features}
+}
+
+func pointFeature(id string, latitude, longitude float64, properties map[string]interface{}) model.GeoJSONFeature {
+	return model.GeoJSONFeature{
+		Type:       geoJSONFeature,
+		ID:         id,
+		Geometry:   model.GeoJSONGeometry{Type: geoJSONPoint, Coordinates: []float64{longitude, latitude}},
+		Properties: properties,
+	}
+}
+
+// locationFromFeature reads a location from a decoded Point feature. It does
+// not validate the result beyond the shape of the feature.
+func locationFromFeature(feature *model.GeoJSONFeature) (model.CreateLocationRequest, error) {
+	if feature.Geometry.Type != geoJSONPoint {
+		return model.CreateLocationRequest{}, errFeatureNotPoint
+	}
+
+	coordinates, ok := feature.Geometry.Coordinates.([]interface{})
+	if !ok || len(coordinates) < 2 {
+		return model.CreateLocationRequest{}, errFeatureCoordinates
+	}
+
+	longitude, lonOK := coordinates[0].(float64)
+	latitude, latOK := coordinates[1].(float64)
+
+	if !lonOK || !latOK {
+		return model.CreateLocationRequest{}, errFeatureCoordinates
+	}
+
+	name, err := stringProperty(feature.Properties, "name")
+	if err != nil {
+		return model.CreateLocationRequest{}, err
+	}
+
+	markerColor, err := stringProperty(feature.Properties, "marker_color")
+	if err != nil {
+		return model.CreateLocationRequest{}, err
+	}
+
+	return model.CreateLocationRequest{
+		Name:        name,
+		Latitude:    &latitude,
+		Longitude:   &longitude,
+		MarkerColor: markerColor,
+	}, nil
+}
+
+func stringProperty(properties map[string]interface{}, key string) (string, error) {
+	value, ok := properties[key]
+	if !ok || value == nil {
+		return "", nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s property must be a string", key)
+	}
+
+	return text, nil
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"encoding/json"
+	"location-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutesFeatureCollection(t *testing.T) {
+	res := &model.GetRoutesResponse{
+		Routes: []model.Route{
+			{ID: "1", Name: "first", Latitude: 40.5, Longitude: 32.5, Distance: 1.5, MarkerColor: "FFFFFF"},
+			{ID: "2", Name: "second", Latitude: 41, Longitude: 33, Distance: 2.5, MarkerColor: "000000"},
+		},
+	}
+
+	t.Run("should return one point feature per stop", func(t *testing.T) {
+		collection := routesFeatureCollection(res, false)
+
+		assert.Equal(t, "FeatureCollection", collection.Type)
+		assert.Len(t, collection.Features, 2)
+		assert.Equal(t, model.GeoJSONFeature{
+			Type:     "Feature",
+			ID:       "1",
+			Geometry: model.GeoJSONGeometry{Type: "Point", Coordinates: []float64{32.5, 40.5}},
+			Properties: map[string]interface{}{
+				"name":         "first",
+				"marker_color": "FFFFFF",
+				"distance":     1.5,
+			},
+		}, collection.Features[0])
+	})
+
+	t.Run("should append a line through the stops in order", func(t *testing.T) {
+		collection := routesFeatureCollection(res, true)
+
+		assert.Len(t, collection.Features, 3)
+		assert.Equal(t, model.GeoJSONGeometry{
+			Type:        "LineString",
+			Coordinates: [][]float64{{32.5, 40.5}, {33, 41}},
+		}, collection.Features[2].Geometry)
+	})
+}
+
+func TestLocationFromFeature(t *testing.T) {
+	decode := func(t *testing.T, data string) *model.GeoJSONFeature {
+		var feature model.GeoJSONFeature
+		if err := json.Unmarshal([]byte(data), &feature); err != nil {
+			t.Fatalf("Failed to decode feature: %v", err)
+		}
+
+		return &feature
+	}
+
+	t.Run("should read a point feature", func(t *testing.T) {
+		location, err := locationFromFeature(decode(t,
+			`{"type":"Feature","geometry":{"type":"Point","coordinates":[32.5,40.5]},"properties":{"name":"first","marker_color":"FFFFFF"}}`,
+		))
+
+		assert.Nil(t, err)
+		assert.Equal(t, model.CreateLocationRequest{Name: "first", Latitude: float64Ptr(40.5), Longitude: float64Ptr(32.5), MarkerColor: "FFFFFF"}, location)
+	})
+
+	t.Run("should reject other geometries and malformed points", func(t *testing.T) {
+		_, err := locationFromFeature(decode(t,
+			`{"type":"Feature","geometry":{"type":"LineString","coordinates":[[32.5,40.5],[33,41]]},"properties":{}}`,
+		))
+		assert.Equal(t, errFeatureNotPoint, err)
+
+		_, err = locationFromFeature(decode(t,
+			`{"type":"Feature","geometry":{"type":"Point","coordinates":["32.5",40.5]},"properties":{}}`,
+		))
+		assert.Equal(t, errFeatureCoordinates, err)
+
+		_, err = locationFromFeature(decode(t,
+			`{"type":"Feature","geometry":{"type":"Point","coordinates":[32.5,40.5]},"properties":{"name":1}}`,
+		))
+		assert.EqualError(t, err, "name property must be a string")
+	})
+}
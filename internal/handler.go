@@ -1,34 +1,127 @@
 package internal
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"location-api/internal/encoder"
 	"location-api/model"
+	"log"
+	"mime"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const (
+	mimeGeoJSON    = "application/geo+json"
+	mimeMergePatch = "application/merge-patch+json"
+)
+
+// headerActor names the caller that changes are recorded against in the history.
+const headerActor = "X-Actor"
+
+var (
+	errIfMatchSingle       = errors.New("If-Match can only guard an update of a single location")
+	errIfMatchWithExpected = errors.New("use either If-Match or expected_version, not both")
+	errIfMatchInvalid      = errors.New(`If-Match must be a quoted version such as "3"`)
+)
+
+const defaultMatrixMaxElements = 250000
+const defaultMatrixStreamThreshold = 10000
+
 type Handler struct {
-	service actions
+	service               actions
+	matrixMaxElements     int
+	matrixStreamThreshold int
+}
+
+type HandlerOption func(*Handler)
+
+// WithDistanceMatrixLimits sets the largest distance matrix, in origins times
+// destinations, that is served and the size above which it is streamed.
+// Values below one keep the defaults.
+func WithDistanceMatrixLimits(maxElements, streamThreshold int) HandlerOption {
+	return func(h *Handler) {
+		if maxElements > 0 {
+			h.matrixMaxElements = maxElements
+		}
+
+		if streamThreshold > 0 {
+			h.matrixStreamThreshold = streamThreshold
+		}
+	}
 }
 
 type actions interface {
 	CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error)
+	CreateLocations(req *model.CreateLocationsRequest) (*model.CreateLocationsResponse, error)
+	CreateLocationsFromGeoJSON(req *model.ImportGeoJSONRequest) (*model.CreateLocationsResponse, error)
 	GetLocation(req *model.GetLocationRequest) (*model.GetLocationResponse, error)
+	GetLocationHistory(req *model.GetLocationHistoryRequest) (*model.GetLocationHistoryResponse, error)
+	RevertLocation(req *model.RevertLocationRequest) (*model.GetLocationResponse, error)
 	GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error)
+	ImportLocations(req *model.ImportLocationsRequest, data io.Reader) (*model.ImportLocationsResponse, error)
+	ExportLocations(req *model.ExportLocationsRequest, w io.Writer) error
 	UpdateLocations(req *model.UpdateLocationsRequest) (*model.UpdateLocationsResponse, error)
+	PatchLocations(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error)
+	DeleteLocation(req *model.DeleteLocationRequest) (*model.DeleteLocationResponse, error)
+	DeleteLocations(req *model.DeleteLocationsRequest) (*model.DeleteLocationsResponse, error)
+	ArchiveLocations(req *model.ArchiveLocationsRequest) (*model.ArchiveLocationsResponse, error)
+	RestoreLocations(req *model.RestoreLocationsRequest) (*model.RestoreLocationsResponse, error)
+	GetArchivedLocations(req *model.GetArchivedLocationsRequest) (*model.GetArchivedLocationsResponse, error)
 	GetRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error)
+	PlanRoute(req *model.PlanRouteRequest) (*model.GetRoutesResponse, error)
+	GetDistanceMatrix(req *model.DistanceMatrixRequest) (*model.DistanceMatrixResponse, error)
+	ResolveDistanceMatrix(req *model.DistanceMatrixRequest) (*model.DistanceMatrixResponse, error)
+	GetNearbyLocations(req *model.GetNearbyLocationsRequest) (*model.GetNearbyLocationsResponse, error)
+	SearchLocations(req *model.SearchLocationsRequest) (*model.SearchLocationsResponse, error)
+	GetLocationsInBox(req *model.GetLocationsInBoxRequest) (*model.GetLocationsResponse, error)
+	GetLocationsInPolygon(req *model.GetLocationsInPolygonRequest) (*model.GetLocationsResponse, error)
 }
 
-func NewHandler(service actions) *Handler {
-	return &Handler{service: service}
+func NewHandler(service actions, options ...HandlerOption) *Handler {
+	h := &Handler{
+		service:               service,
+		matrixMaxElements:     defaultMatrixMaxElements,
+		matrixStreamThreshold: defaultMatrixStreamThreshold,
+	}
+
+	for _, option := range options {
+		option(h)
+	}
+
+	return h
 }
 
 func (h *Handler) RegisterRoutes(app *fiber.App) {
 	app.Post("/location", h.CreateLocation)
 	app.Get("/location", h.GetLocation)
+	app.Get("/location/history", h.GetLocationHistory)
+	app.Post("/location/revert", h.RevertLocation)
+	app.Post("/locations", h.CreateLocations)
 	app.Get("/locations", h.GetLocations)
+	app.Post("/locations/import", h.ImportLocations)
+	app.Get("/locations/export", h.ExportLocations)
+	app.Get("/locations/nearby", h.GetNearbyLocations)
+	app.Get("/locations/search", h.SearchLocations)
+	app.Get("/locations/within-box", h.GetLocationsInBox)
+	app.Post("/locations/within-polygon", h.GetLocationsInPolygon)
 	app.Patch("/locations", h.UpdateLocations)
+	app.Delete("/location", h.DeleteLocation)
+	app.Delete("/locations", h.DeleteLocations)
+	app.Post("/locations/archive", h.ArchiveLocations)
+	app.Post("/locations/restore", h.RestoreLocations)
+	app.Get("/locations/archived", h.GetArchivedLocations)
 	app.Get("/routes", h.GetRoutes)
+	app.Post("/routes", h.PlanRoute)
+	app.Post("/distance-matrix", h.GetDistanceMatrix)
 }
 
 func (h *Handler) CreateLocation(ctx *fiber.Ctx) error {
@@ -41,6 +134,8 @@ func (h *Handler) CreateLocation(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	req.Actor = ctx.Get(headerActor)
+
 	res, err := h.service.CreateLocation(&req)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -49,6 +144,77 @@ func (h *Handler) CreateLocation(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusOK).JSON(res)
 }
 
+// CreateLocations accepts either a list of locations, wrapped in "locations"
+// or as a top level array, or, when sent as application/geo+json, a
+// FeatureCollection of Point features.
+func (h *Handler) CreateLocations(ctx *fiber.Ctx) error {
+	if hasContentType(ctx, mimeGeoJSON) {
+		return h.createLocationsFromGeoJSON(ctx)
+	}
+
+	var req model.CreateLocationsRequest
+
+	var err error
+
+	if body := bytes.TrimLeft(ctx.Body(), " \t\r\n"); len(body) > 0 && body[0] == '[' {
+		err = json.Unmarshal(body, &req.Locations)
+	} else {
+		err = ctx.BodyParser(&req)
+	}
+
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.CreateLocations(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(res.Failed) > 0 && len(res.CreatedIDs) > 0 {
+		return ctx.Status(fiber.StatusPartialContent).JSON(res)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) createLocationsFromGeoJSON(ctx *fiber.Ctx) error {
+	var req model.ImportGeoJSONRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.CreateLocationsFromGeoJSON(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(res.Failed) > 0 && len(res.CreatedIDs) > 0 {
+		return ctx.Status(fiber.StatusPartialContent).JSON(res)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
 func (h *Handler) GetLocation(ctx *fiber.Ctx) error {
 	var req model.GetLocationRequest
 
@@ -61,11 +227,84 @@ func (h *Handler) GetLocation(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID format"})
 	}
 
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	res, err := h.service.GetLocation(&req)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx.Set(fiber.HeaderETag, etag(res.Version))
+
+	if acceptsGeoJSON(ctx) {
+		return ctx.Status(fiber.StatusOK).JSON(locationsFeatureCollection([]model.GetLocationResponse{*res}), mimeGeoJSON)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) GetLocationHistory(ctx *fiber.Ctx) error {
+	var req model.GetLocationHistoryRequest
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	_, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID format"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	res, err := h.service.GetLocationHistory(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) RevertLocation(ctx *fiber.Ctx) error {
+	var req model.RevertLocationRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	_, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID format"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.RevertLocation(&req)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	ctx.Set(fiber.HeaderETag, etag(res.Version))
+
 	return ctx.Status(fiber.StatusOK).JSON(res)
 }
 
@@ -76,15 +315,151 @@ func (h *Handler) GetLocations(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
 	}
 
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	res, err := h.service.GetLocations(&req)
+	if errors.Is(err, model.ErrInvalidCursor) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if acceptsGeoJSON(ctx) {
+		collection := locationsFeatureCollection(res.Locations)
+		collection.NextCursor = res.NextCursor
+		collection.Total = res.Total
+
+		return ctx.Status(fiber.StatusOK).JSON(collection, mimeGeoJSON)
+	}
+
+	if fields := req.SelectedFields(); len(fields) > 0 {
+		selected, err := selectFields(res, fields)
+		if err != nil {
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return ctx.Status(fiber.StatusOK).JSON(selected)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+// selectFields keeps only the requested fields of every location, leaving the
+// paging information as it is.
+func selectFields(res *model.GetLocationsResponse, fields []string) (fiber.Map, error) {
+	locations := make([]map[string]json.RawMessage, 0, len(res.Locations))
+
+	for i := range res.Locations {
+		data, err := json.Marshal(&res.Locations[i])
+		if err != nil {
+			return nil, err
+		}
+
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+
+		location := make(map[string]json.RawMessage, len(fields))
+
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				location[field] = value
+			}
+		}
+
+		locations = append(locations, location)
+	}
+
+	selected := fiber.Map{"locations": locations}
+
+	if res.NextCursor != "" {
+		selected["next_cursor"] = res.NextCursor
+	}
+
+	if res.Total != nil {
+		selected["total"] = *res.Total
+	}
+
+	return selected, nil
+}
+
+// ImportLocations accepts a CSV file either as the raw request body or as the
+// "file" field of a multipart form.
+func (h *Handler) ImportLocations(ctx *fiber.Ctx) error {
+	var req model.ImportLocationsRequest
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	var data io.Reader = bytes.NewReader(ctx.Body())
+
+	if file, err := ctx.FormFile("file"); err == nil {
+		opened, err := file.Open()
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+		}
+		defer opened.Close()
+
+		data = opened
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.ImportLocations(&req, data)
+	if errors.Is(err, model.ErrInvalidCSV) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	if !req.DryRun && len(res.Failed) > 0 && len(res.CreatedIDs) > 0 {
+		return ctx.Status(fiber.StatusPartialContent).JSON(res)
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(res)
 }
 
+func (h *Handler) ExportLocations(ctx *fiber.Ctx) error {
+	var req model.ExportLocationsRequest
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	enc, ok := encoder.Lookup(exportFormat(&req))
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": model.ErrUnsupportedFormat.Error()})
+	}
+
+	ctx.Set(fiber.HeaderContentType, enc.ContentType())
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="locations.%s"`, enc.FileExtension()))
+
+	// The body is written after the handler returns, so a failure half way can
+	// only be logged; the client sees a truncated file.
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := h.service.ExportLocations(&req, w); err != nil {
+			log.Println("ERROR: locations cannot export:", err)
+		}
+	})
+
+	return nil
+}
+
+// UpdateLocations treats zero values as "keep the current value". Requests sent
+// as application/merge-patch+json are applied as JSON Merge Patches instead.
 func (h *Handler) UpdateLocations(ctx *fiber.Ctx) error {
+	if hasContentType(ctx, mimeMergePatch) {
+		return h.patchLocations(ctx)
+	}
+
 	var req model.UpdateLocationsRequest
 
 	if err := ctx.BodyParser(&req); err != nil {
@@ -104,18 +479,233 @@ func (h *Handler) UpdateLocations(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	version, err := ifMatchVersion(ctx, len(req.Locations), req.Locations[0].ExpectedVersion)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if version != nil {
+		req.Locations[0].ExpectedVersion = version
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
 	res, err := h.service.UpdateLocations(&req)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	if len(res.FailedIDs) > 0 && len(res.UpdatedIDs) > 0 {
+	return ctx.Status(updateStatus(res, version != nil)).JSON(res)
+}
+
+func (h *Handler) patchLocations(ctx *fiber.Ctx) error {
+	var req model.PatchLocationsRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	version, err := ifMatchVersion(ctx, len(req.Locations), req.Locations[0].ExpectedVersion)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if version != nil {
+		req.Locations[0].ExpectedVersion = version
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.PatchLocations(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(updateStatus(res, version != nil)).JSON(res)
+}
+
+// ifMatchVersion reads the version an If-Match header expects. The header only
+// guards a request for a single location and "*" matches any version. It
+// returns nil when there is nothing to check.
+func ifMatchVersion(ctx *fiber.Ctx, locations int, expectedVersion *int64) (*int64, error) {
+	header := strings.TrimSpace(ctx.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	if locations != 1 {
+		return nil, errIfMatchSingle
+	}
+
+	if expectedVersion != nil {
+		return nil, errIfMatchWithExpected
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) || version < 1 {
+		return nil, errIfMatchInvalid
+	}
+
+	return &version, nil
+}
+
+// updateStatus picks the status of an update: 206 when only some locations
+// were updated, and 409 when every location conflicted, or 412 when that
+// conflict came from an If-Match header.
+func updateStatus(res *model.UpdateLocationsResponse, ifMatch bool) int {
+	switch {
+	case len(res.UpdatedIDs) > 0 && (len(res.FailedIDs) > 0 || len(res.ConflictIDs) > 0):
+		return fiber.StatusPartialContent
+	case len(res.UpdatedIDs) == 0 && len(res.FailedIDs) == 0 && len(res.ConflictIDs) > 0 && ifMatch:
+		return fiber.StatusPreconditionFailed
+	case len(res.UpdatedIDs) == 0 && len(res.FailedIDs) == 0 && len(res.ConflictIDs) > 0:
+		return fiber.StatusConflict
+	default:
+		return fiber.StatusOK
+	}
+}
+
+// etag is the strong entity tag of a location version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func (h *Handler) DeleteLocation(ctx *fiber.Ctx) error {
+	var req model.DeleteLocationRequest
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	_, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid ID format"})
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.DeleteLocation(&req)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) DeleteLocations(ctx *fiber.Ctx) error {
+	var req model.DeleteLocationsRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.DeleteLocations(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(res.FailedIDs) > 0 && len(res.DeletedIDs) > 0 {
+		return ctx.Status(fiber.StatusPartialContent).JSON(res)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) ArchiveLocations(ctx *fiber.Ctx) error {
+	var req model.ArchiveLocationsRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.ArchiveLocations(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(res.FailedIDs) > 0 && len(res.ArchivedIDs) > 0 {
 		return ctx.Status(fiber.StatusPartialContent).JSON(res)
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(res)
 }
 
+func (h *Handler) RestoreLocations(ctx *fiber.Ctx) error {
+	var req model.RestoreLocationsRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	req.Actor = ctx.Get(headerActor)
+
+	res, err := h.service.RestoreLocations(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(res.FailedIDs) > 0 && len(res.RestoredIDs) > 0 {
+		return ctx.Status(fiber.StatusPartialContent).JSON(res)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) GetArchivedLocations(ctx *fiber.Ctx) error {
+	var req model.GetArchivedLocationsRequest
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	res, err := h.service.GetArchivedLocations(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
 func (h *Handler) GetRoutes(ctx *fiber.Ctx) error {
 	var req model.GetRoutesRequest
 
@@ -127,10 +717,272 @@ func (h *Handler) GetRoutes(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	enc, err := routesEncoder(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	res, err := h.service.GetRoutes(&req)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	return routesResponse(ctx, fiber.StatusOK, res, enc)
+}
+
+func (h *Handler) PlanRoute(ctx *fiber.Ctx) error {
+	var req model.PlanRouteRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	enc, err := routesEncoder(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	res, err := h.service.PlanRoute(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(res.MissingIDs) > 0 && len(res.Routes) > 0 {
+		return routesResponse(ctx, fiber.StatusPartialContent, res, enc)
+	}
+
+	return routesResponse(ctx, fiber.StatusOK, res, enc)
+}
+
+// routesEncoder returns the encoder named by the format query parameter, or nil
+// for the JSON response when no format or "json" is given.
+func routesEncoder(ctx *fiber.Ctx) (encoder.Encoder, error) {
+	format := ctx.Query("format")
+	if format == "" || strings.EqualFold(format, "json") {
+		return nil, nil
+	}
+
+	enc, ok := encoder.Lookup(format)
+	if !ok {
+		return nil, model.ErrUnsupportedFormat
+	}
+
+	return enc, nil
+}
+
+// routesResponse writes res with enc when a format was asked for. Otherwise it
+// writes JSON, or a FeatureCollection when the client accepts GeoJSON, where
+// line=true adds a LineString through the stops.
+func routesResponse(ctx *fiber.Ctx, status int, res *model.GetRoutesResponse, enc encoder.Encoder) error {
+	if res != nil && enc != nil {
+		ctx.Status(status).Set(fiber.HeaderContentType, enc.ContentType())
+		return enc.EncodeRoutes(ctx, res)
+	}
+
+	if res != nil && acceptsGeoJSON(ctx) {
+		return ctx.Status(status).JSON(routesFeatureCollection(res, ctx.QueryBool("line")), mimeGeoJSON)
+	}
+
+	return ctx.Status(status).JSON(res)
+}
+
+// hasContentType reports whether the request body is of the given media type,
+// ignoring case and parameters such as charset.
+func hasContentType(ctx *fiber.Ctx, mediaType string) bool {
+	contentType, _, err := mime.ParseMediaType(string(ctx.Request().Header.ContentType()))
+
+	return err == nil && strings.EqualFold(contentType, mediaType)
+}
+
+// acceptsGeoJSON reports whether the client prefers GeoJSON over plain JSON.
+func acceptsGeoJSON(ctx *fiber.Ctx) bool {
+	return ctx.Accepts(fiber.MIMEApplicationJSON, mimeGeoJSON) == mimeGeoJSON
+}
+
+func (h *Handler) GetDistanceMatrix(ctx *fiber.Ctx) error {
+	var req model.DistanceMatrixRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	elements := len(req.Origins) * len(req.Destinations)
+	if elements > h.matrixMaxElements {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Distance matrix has %d elements, the limit is %d", elements, h.matrixMaxElements),
+		})
+	}
+
+	// Large matrices are only resolved here; their rows are computed while
+	// they are written so the whole matrix is never held in memory.
+	stream := elements > h.matrixStreamThreshold
+
+	var res *model.DistanceMatrixResponse
+
+	var err error
+
+	if stream {
+		res, err = h.service.ResolveDistanceMatrix(&req)
+	} else {
+		res, err = h.service.GetDistanceMatrix(&req)
+	}
+
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if len(res.MissingIDs) > 0 {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":       "Locations not found",
+			"missing_ids": res.MissingIDs,
+		})
+	}
+
+	if !stream {
+		return ctx.Status(fiber.StatusOK).JSON(res)
+	}
+
+	ctx.Status(fiber.StatusOK)
+	ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := writeDistanceMatrix(w, res); err != nil {
+			log.Println("ERROR: distance matrix stream failed:", err)
+		}
+	})
+
+	return nil
+}
+
+// writeDistanceMatrix encodes res the same way as the JSON response but computes
+// and writes one matrix row at a time, flushing after each so large matrices go
+// out chunked.
+func writeDistanceMatrix(w *bufio.Writer, res *model.DistanceMatrixResponse) error {
+	origins, err := json.Marshal(res.Origins)
+	if err != nil {
+		return err
+	}
+
+	destinations, err := json.Marshal(res.Destinations)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, `{"origins":%s,"destinations":%s,"distances":[`, origins, destinations); err != nil {
+		return err
+	}
+
+	for i, origin := range res.Origins {
+		if i > 0 {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+
+		encoded, err := json.Marshal(distanceMatrixRow(origin, res.Destinations))
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.WriteString("]}"); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func (h *Handler) GetNearbyLocations(ctx *fiber.Ctx) error {
+	var req model.GetNearbyLocationsRequest
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	res, err := h.service.GetNearbyLocations(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) SearchLocations(ctx *fiber.Ctx) error {
+	var req model.SearchLocationsRequest
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	res, err := h.service.SearchLocations(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) GetLocationsInBox(ctx *fiber.Ctx) error {
+	var req model.GetLocationsInBoxRequest
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	res, err := h.service.GetLocationsInBox(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
+
+func (h *Handler) GetLocationsInPolygon(ctx *fiber.Ctx) error {
+	var req model.GetLocationsInPolygonRequest
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := ctx.QueryParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := req.ValidateLocation(); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	res, err := h.service.GetLocationsInPolygon(&req)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(res)
 }
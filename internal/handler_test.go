@@ -2,12 +2,19 @@ package internal
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"location-api/internal/cache"
 	"location-api/model"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
 	gomock "go.uber.org/mock/gomock"
 
 	"github.com/gofiber/fiber/v2"
@@ -43,6 +50,2244 @@ func TestHandler_CreateLocation(t *testing.T) {
 		assert.Equal(t, http.StatusOK, res.StatusCode)
 	})
 
+	t.Run("should accept a location on the equator and the prime meridian", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			CreateLocation(&model.CreateLocationRequest{
+				Name:        "null island",
+				Latitude:    float64Ptr(0),
+				Longitude:   float64Ptr(0),
+				MarkerColor: "FFFFFF",
+			}).
+			Return(&testCreateLocationRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/location",
+			bytes.NewReader([]byte(`{"name": "null island", "latitude": 0, "longitude": 0, "marker_color": "FFFFFF"}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return bad request for coordinates off the earth or missing", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		for _, body := range []string{
+			`{"name": "test", "latitude": 124.1222, "longitude": 1.1, "marker_color": "FFFFFF"}`,
+			`{"name": "test", "latitude": 1.1, "longitude": -180.5, "marker_color": "FFFFFF"}`,
+			`{"name": "test", "longitude": 1.1, "marker_color": "FFFFFF"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/location", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			res, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+			res.Body.Close()
+		}
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			CreateLocation(&testCreateLocationReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/location",
+			bytes.NewReader([]byte(`{"name": "test", "latitude": 1.1, "longitude": 1.1, "marker_color": "FFFFFF"}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("should return bad request error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/location",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+	t.Run("should return validation error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/location",
+			bytes.NewReader([]byte(`{"name": "test", "latitude": 1.1, "longitude": 1.1}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_CreateLocations(t *testing.T) {
+	t.Run("should create locations properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			CreateLocations(&model.CreateLocationsRequest{
+				Locations: []model.CreateLocationRequest{testCreateLocationReq},
+			}).
+			Return(&model.CreateLocationsResponse{
+				CreatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				Failed:       []model.CreateLocationError{},
+				CreatedCount: 1,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations",
+			bytes.NewReader([]byte(`{"locations": [{"name": "test", "latitude": 1.1, "longitude": 1.1, "marker_color": "FFFFFF"}]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should create locations from a top level array", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			CreateLocations(&model.CreateLocationsRequest{
+				Locations: []model.CreateLocationRequest{testCreateLocationReq},
+			}).
+			Return(&model.CreateLocationsResponse{
+				CreatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				Failed:       []model.CreateLocationError{},
+				CreatedCount: 1,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations",
+			bytes.NewReader([]byte(` [{"name": "test", "latitude": 1.1, "longitude": 1.1, "marker_color": "FFFFFF"}]`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return partial content", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			CreateLocations(gomock.Any()).
+			Return(&model.CreateLocationsResponse{
+				CreatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				Failed:       []model.CreateLocationError{{Index: 1, Error: "invalid"}},
+				CreatedCount: 1,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations",
+			bytes.NewReader([]byte(`{"locations": [{"name": "test", "latitude": 1.1, "longitude": 1.1, "marker_color": "FFFFFF"}, {"name": "x"}]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+	})
+
+	t.Run("should return bad request when no locations are given", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodPost, "/locations", bytes.NewReader([]byte(`{"locations": []}`)))
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_CreateLocationsFromGeoJSON(t *testing.T) {
+	t.Run("should create locations from a feature collection", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			CreateLocationsFromGeoJSON(gomock.Any()).
+			Return(&model.CreateLocationsResponse{
+				CreatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				Failed:       []model.CreateLocationError{},
+				CreatedCount: 1,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations",
+			strings.NewReader(`{"type":"FeatureCollection","features":[{"type":"Feature",`+
+				`"geometry":{"type":"Point","coordinates":[1.1,1.1]},"properties":{"name":"test","marker_color":"FFFFFF"}}]}`),
+		)
+		req.Header.Set("Content-Type", "application/geo+json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return bad request when it is not a feature collection", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations",
+			strings.NewReader(`{"type":"Feature","geometry":{"type":"Point","coordinates":[1.1,1.1]}}`),
+		)
+		req.Header.Set("Content-Type", "application/geo+json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_ExportLocationsFormats(t *testing.T) {
+	t.Run("should name the download after the encoder", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			ExportLocations(&model.ExportLocationsRequest{Format: "kml"}, gomock.Any()).
+			Return(nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/locations/export?format=kml", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/vnd.google-earth.kml+xml", res.Header.Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="locations.kml"`, res.Header.Get("Content-Disposition"))
+	})
+}
+
+func TestHandler_GetRoutesFormats(t *testing.T) {
+	t.Run("should encode routes as gpx", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetRoutes(gomock.Any()).
+			Return(&model.GetRoutesResponse{Routes: []model.Route{
+				{ID: "1", Name: "first", Latitude: 40.5, Longitude: 32.5, Distance: 1.5, MarkerColor: "FFFFFF"},
+			}}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/routes?latitude=40&longitude=32&format=gpx", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/gpx+xml", res.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(res.Body)
+		assert.Nil(t, err)
+		assert.Contains(t, string(body), `<rtept lat="40.5" lon="32.5"><name>first</name></rtept>`)
+	})
+
+	t.Run("should return bad request for an unknown format", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/routes?latitude=40&longitude=32&format=xlsx", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_GetLocationsGeoJSON(t *testing.T) {
+	t.Run("should return a feature collection when geojson is accepted", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocations(gomock.Any()).
+			Return(&model.GetLocationsResponse{Locations: []model.GetLocationResponse{testGetLocationRes}}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/locations?page=1", http.NoBody)
+		req.Header.Set("Accept", "application/geo+json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/geo+json", res.Header.Get("Content-Type"))
+
+		var collection model.GeoJSONFeatureCollection
+
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&collection))
+		assert.Equal(t, "FeatureCollection", collection.Type)
+		assert.Len(t, collection.Features, 1)
+		assert.Equal(t, testGetLocationRes.ID, collection.Features[0].ID)
+	})
+
+	t.Run("should keep the next cursor and total", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		total := int64(12)
+
+		mockService.
+			EXPECT().
+			GetLocations(gomock.Any()).
+			Return(&model.GetLocationsResponse{
+				Locations:  []model.GetLocationResponse{testGetLocationRes},
+				NextCursor: "next",
+				Total:      &total,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/locations?limit=1&total=true", http.NoBody)
+		req.Header.Set("Accept", "application/geo+json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var collection model.GeoJSONFeatureCollection
+
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&collection))
+		assert.Len(t, collection.Features, 1)
+		assert.Equal(t, "next", collection.NextCursor)
+		assert.Equal(t, &total, collection.Total)
+	})
+}
+
+func TestHandler_GetRoutesGeoJSON(t *testing.T) {
+	t.Run("should include a line string when asked", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetRoutes(gomock.Any()).
+			Return(&model.GetRoutesResponse{Routes: []model.Route{
+				{ID: "1", Name: "first", Latitude: 40.5, Longitude: 32.5, Distance: 1.5, MarkerColor: "FFFFFF"},
+				{ID: "2", Name: "second", Latitude: 41, Longitude: 33, Distance: 2.5, MarkerColor: "000000"},
+			}}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/routes?latitude=40&longitude=32&line=true", http.NoBody)
+		req.Header.Set("Accept", "application/geo+json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var collection model.GeoJSONFeatureCollection
+
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&collection))
+		assert.Len(t, collection.Features, 3)
+		assert.Equal(t, "LineString", collection.Features[2].Geometry.Type)
+	})
+}
+
+func TestHandler_GetLocation(t *testing.T) {
+	t.Run("should get location properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocation(&testGetLocationReq).
+			Return(&testGetLocationRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/location?id=67d562e3d9f2d225ca4d9918",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, `"3"`, res.Header.Get("ETag"))
+	})
+
+	t.Run("should send the stored version as ETag before and after it is cached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		app := createTestApp()
+
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			GetLocation(&testGetLocationReq).
+			Return(&testGetLocationRes, nil).
+			Times(1)
+
+		handler := NewHandler(NewService(mockRepository, cache.NewMemoryCache(0)))
+		handler.RegisterRoutes(app)
+
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/location?id=67d562e3d9f2d225ca4d9918", http.NoBody)
+
+			res, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, fmt.Sprintf(`"%d"`, testGetLocationRes.Version), res.Header.Get("ETag"))
+
+			var body model.GetLocationResponse
+			assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+			assert.Equal(t, testGetLocationRes.Version, body.Version)
+			res.Body.Close()
+		}
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocation(&testGetLocationReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/location?id=67d562e3d9f2d225ca4d9918",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("should return bad request error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/location?id=test",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should return not found for an as_of before the location was created", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocation(&model.GetLocationRequest{ID: "67d562e3d9f2d225ca4d9918", AsOf: "2020-01-01T00:00:00Z"}).
+			Return(nil, mongo.ErrNoDocuments).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/location?id=67d562e3d9f2d225ca4d9918&as_of=2020-01-01T00:00:00Z",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Empty(t, res.Header.Get("ETag"))
+	})
+
+	t.Run("should reject an as_of that is not a timestamp", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/location?id=67d562e3d9f2d225ca4d9918&as_of=yesterday",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_RevertLocation(t *testing.T) {
+	version := int64(2)
+	revertReq := &model.RevertLocationRequest{ID: "67d562e3d9f2d225ca4d9918", Version: &version}
+
+	t.Run("should revert location properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			RevertLocation(revertReq).
+			Return(&testGetLocationRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/location/revert",
+			bytes.NewReader([]byte(`{"id": "67d562e3d9f2d225ca4d9918", "version": 2}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, `"3"`, res.Header.Get("ETag"))
+	})
+
+	t.Run("should return not found error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			RevertLocation(revertReq).
+			Return(nil, mongo.ErrNoDocuments).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/location/revert",
+			bytes.NewReader([]byte(`{"id": "67d562e3d9f2d225ca4d9918", "version": 2}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("should require exactly one of version and as_of", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		for _, body := range []string{
+			`{"id": "67d562e3d9f2d225ca4d9918"}`,
+			`{"id": "67d562e3d9f2d225ca4d9918", "version": 2, "as_of": "2025-03-15T12:00:00Z"}`,
+			`{"id": "67d562e3d9f2d225ca4d9918", "as_of": "yesterday"}`,
+		} {
+			req := httptest.NewRequest(http.MethodPost, "/location/revert", bytes.NewReader([]byte(body)))
+			req.Header.Set("Content-Type", "application/json")
+
+			res, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+			res.Body.Close()
+		}
+	})
+}
+
+func TestHandler_GetLocationHistory(t *testing.T) {
+	historyReq := &model.GetLocationHistoryRequest{ID: "67d562e3d9f2d225ca4d9918", Page: 1, Limit: 20}
+
+	t.Run("should get location history properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocationHistory(historyReq).
+			Return(&model.GetLocationHistoryResponse{History: []model.LocationHistoryEntry{{
+				LocationID: "67d562e3d9f2d225ca4d9918",
+				Action:     model.HistoryActionCreate,
+				Version:    1,
+				Actor:      "jane",
+				After:      &model.LocationState{Name: "test", Latitude: 1.1, Longitude: 1.1, MarkerColor: "FFFFFF"},
+			}}}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/location/history?id=67d562e3d9f2d225ca4d9918&page=1&limit=20",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocationHistory(historyReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/location/history?id=67d562e3d9f2d225ca4d9918&page=1&limit=20",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("should return bad request error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/location/history?id=test", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_GetLocations(t *testing.T) {
+	t.Run("should get location properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocations(&testGetLocationsReq).
+			Return(&testGetLocationsRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/locations?page=1&limit=1",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocations(&testGetLocationsReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/locations?page=1&limit=1",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("should return bad request error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/locations?page=1&limit=test",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should reject an invalid cursor", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocations(&model.GetLocationsRequest{Cursor: "bad"}).
+			Return(nil, model.ErrInvalidCursor).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/locations?cursor=bad", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should reject a limit over the maximum or a page with a cursor", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		for _, target := range []string{"/locations?limit=1001", "/locations?page=2&cursor=NjdkNTYy"} {
+			req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+
+			res, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, target)
+			res.Body.Close()
+		}
+	})
+
+	t.Run("should return only the selected fields", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetLocations(&model.GetLocationsRequest{Sort: "-name", Fields: "id,name"}).
+			Return(&model.GetLocationsResponse{Locations: []model.GetLocationResponse{testGetLocationRes}, NextCursor: "next"}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/locations?sort=-name&fields=id,name", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		body, _ := io.ReadAll(res.Body)
+		assert.JSONEq(t, `{"locations":[{"id":"test","name":"test"}],"next_cursor":"next"}`, string(body))
+	})
+
+	t.Run("should reject filters outside the allow-list", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		for _, target := range []string{
+			"/locations?sort=-$where",
+			"/locations?sort=deleted_at",
+			"/locations?fields=name,deleted_at",
+			"/locations?marker_color=%7B%22$ne%22:1%7D",
+			"/locations?created_after=yesterday",
+		} {
+			req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+
+			res, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, target)
+			res.Body.Close()
+		}
+	})
+}
+
+func TestHandler_ImportLocations(t *testing.T) {
+	data := "name,latitude,longitude,marker_color\ntest,1.1,1.1,FFFFFF\n"
+
+	t.Run("should import locations from the request body", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			ImportLocations(&model.ImportLocationsRequest{DryRun: true}, gomock.Any()).
+			DoAndReturn(func(_ *model.ImportLocationsRequest, r io.Reader) (*model.ImportLocationsResponse, error) {
+				body, err := io.ReadAll(r)
+				assert.Nil(t, err)
+				assert.Equal(t, data, string(body))
+
+				return &model.ImportLocationsResponse{CreatedIDs: []string{}, ValidCount: 1, DryRun: true}, nil
+			}).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodPost, "/locations/import?dry_run=true", strings.NewReader(data))
+		req.Header.Set("Content-Type", "text/csv")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should import locations from a multipart file", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			ImportLocations(&model.ImportLocationsRequest{}, gomock.Any()).
+			DoAndReturn(func(_ *model.ImportLocationsRequest, r io.Reader) (*model.ImportLocationsResponse, error) {
+				body, err := io.ReadAll(r)
+				assert.Nil(t, err)
+				assert.Equal(t, data, string(body))
+
+				return &model.ImportLocationsResponse{
+					CreatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+					Failed:       []model.ImportLocationError{{Line: 3, Error: "invalid"}},
+					CreatedCount: 1,
+				}, nil
+			}).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		var body bytes.Buffer
+
+		writer := multipart.NewWriter(&body)
+		part, err := writer.CreateFormFile("file", "locations.csv")
+		assert.Nil(t, err)
+
+		_, err = part.Write([]byte(data))
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/locations/import", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+	})
+
+	t.Run("should return bad request for an invalid csv", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			ImportLocations(&model.ImportLocationsRequest{}, gomock.Any()).
+			Return(nil, fmt.Errorf("%w: missing column %q", model.ErrInvalidCSV, "latitude")).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodPost, "/locations/import", strings.NewReader("name\ntest\n"))
+		req.Header.Set("Content-Type", "text/csv")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_ExportLocations(t *testing.T) {
+	t.Run("should stream locations as csv", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			ExportLocations(&model.ExportLocationsRequest{Format: "csv"}, gomock.Any()).
+			DoAndReturn(func(_ *model.ExportLocationsRequest, w io.Writer) error {
+				_, err := io.WriteString(w, "id,name,latitude,longitude,marker_color\n")
+				return err
+			}).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/locations/export?format=csv", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/csv; charset=utf-8", res.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(res.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, "id,name,latitude,longitude,marker_color\n", string(body))
+	})
+
+	t.Run("should return bad request for an unknown format", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/locations/export?format=xlsx", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_UpdateLocations(t *testing.T) {
+	t.Run("should update locations properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			UpdateLocations(&testUpdateLocationsReq).
+			Return(&testUpdateLocationsRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		reqBody := `{
+			"locations": [
+				{
+					"id": "67d562e3d9f2d225ca4d9918",
+					"name": "test",
+					"latitude": 1.1,
+					"longitude": 1.1,
+					"marker_color": "FFFFFF"
+				},
+				{
+					"id": "67d562e3d9f2d225ca4d9919",
+					"name": "test2",
+					"latitude": 2.2,
+					"longitude": 2.2,
+					"marker_color": "000000"
+				}
+			]
+		}`
+
+		req := httptest.NewRequest(
+			http.MethodPatch,
+			"/locations",
+			bytes.NewReader([]byte(reqBody)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var body map[string]interface{}
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+
+		for _, field := range []string{"updated_ids", "failed_ids", "conflict_ids"} {
+			assert.IsType(t, []interface{}{}, body[field], field)
+		}
+	})
+
+	t.Run("should return partial content", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			UpdateLocations(&testUpdateLocationsReqForPartialContent).
+			Return(&model.UpdateLocationsResponse{
+				UpdatedIDs:   []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+				FailedIDs:    []string{"67d562e3d9ddd225ca4d9919"},
+				UpdatedCount: 2,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		reqBody := `{
+			"locations": [
+				{
+					"id": "67d562e3d9f2d225ca4d9918",
+					"name": "test",
+					"latitude": 1.1,
+					"longitude": 1.1,
+					"marker_color": "FFFFFF"
+				},
+				{
+					"id": "67d562e3d9f2d225ca4d9919",
+					"name": "test2",
+					"latitude": 2.2,
+					"longitude": 2.2,
+					"marker_color": "000000"
+				},
+				{
+					"id": "67d562e3d9ddd225ca4d9919",
+					"name": "test3",
+					"latitude": 3.3,
+					"longitude": 3.3,
+					"marker_color": "000000"
+				}
+			]
+		}`
+
+		req := httptest.NewRequest(
+			http.MethodPatch,
+			"/locations",
+			bytes.NewReader([]byte(reqBody)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+	})
+
+	t.Run("should guard a single location with If-Match", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		expected := int64(3)
+
+		mockService.
+			EXPECT().
+			UpdateLocations(&model.UpdateLocationsRequest{
+				Locations: []model.UpdateLocation{{ID: "67d562e3d9f2d225ca4d9918", Name: "test", ExpectedVersion: &expected}},
+			}).
+			Return(&model.UpdateLocationsResponse{ConflictIDs: []string{"67d562e3d9f2d225ca4d9918"}}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPatch,
+			"/locations",
+			bytes.NewReader([]byte(`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "name": "test"}]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"3"`)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+	})
+
+	t.Run("should return conflict when every location is stale", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			UpdateLocations(&testUpdateLocationsReq).
+			Return(&model.UpdateLocationsResponse{
+				ConflictIDs: []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		reqBody := `{
+			"locations": [
+				{
+					"id": "67d562e3d9f2d225ca4d9918",
+					"name": "test",
+					"latitude": 1.1,
+					"longitude": 1.1,
+					"marker_color": "FFFFFF"
+				},
+				{
+					"id": "67d562e3d9f2d225ca4d9919",
+					"name": "test2",
+					"latitude": 2.2,
+					"longitude": 2.2,
+					"marker_color": "000000"
+				}
+			]
+		}`
+
+		req := httptest.NewRequest(
+			http.MethodPatch,
+			"/locations",
+			bytes.NewReader([]byte(reqBody)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+
+	t.Run("should reject an If-Match header it cannot apply", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		for _, tc := range []struct {
+			body    string
+			ifMatch string
+		}{
+			{`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "name": "test"}]}`, `W/"3"`},
+			{`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "name": "test", "expected_version": 3}]}`, `"3"`},
+			{`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "name": "test"}, {"id": "67d562e3d9f2d225ca4d9919", "name": "test"}]}`, `"3"`},
+		} {
+			req := httptest.NewRequest(http.MethodPatch, "/locations", bytes.NewReader([]byte(tc.body)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", tc.ifMatch)
+
+			res, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, tc.body)
+			res.Body.Close()
+		}
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			UpdateLocations(&testUpdateLocationsReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		reqBody := `{
+			"locations": [
+				{
+					"id": "67d562e3d9f2d225ca4d9918",
+					"name": "test",
+					"latitude": 1.1,
+					"longitude": 1.1,
+					"marker_color": "FFFFFF"
+				},
+				{
+					"id": "67d562e3d9f2d225ca4d9919",
+					"name": "test2",
+					"latitude": 2.2,
+					"longitude": 2.2,	
+					"marker_color": "000000"
+				}
+			]
+		}`
+
+		req := httptest.NewRequest(
+			http.MethodPatch,
+			"/locations",
+			bytes.NewReader([]byte(reqBody)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("should return bad request error when body is empty", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPatch,
+			"/locations",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_PatchLocations(t *testing.T) {
+	t.Run("should apply a merge patch per location", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			PatchLocations(gomock.Any()).
+			DoAndReturn(func(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error) {
+				assert.Len(t, req.Locations, 1)
+				assert.Equal(t, "67d562e3d9f2d225ca4d9918", req.Locations[0].ID)
+
+				set, unset, err := req.Locations[0].Changes()
+				assert.Nil(t, err)
+				assert.Equal(t, map[string]interface{}{"name": "renamed", "latitude": 0.0}, set)
+				assert.Equal(t, []string{"marker_color"}, unset)
+
+				return &model.UpdateLocationsResponse{
+					UpdatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+					FailedIDs:    []string{},
+					UpdatedCount: 1,
+				}, nil
+			}).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPatch,
+			"/locations",
+			strings.NewReader(`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "name": "renamed", "latitude": 0, "marker_color": null}]}`),
+		)
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should match the merge patch media type ignoring case and parameters", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			PatchLocations(gomock.Any()).
+			Return(&model.UpdateLocationsResponse{
+				UpdatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				FailedIDs:    []string{},
+				UpdatedCount: 1,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPatch,
+			"/locations",
+			strings.NewReader(`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "marker_color": null}]}`),
+		)
+		req.Header.Set("Content-Type", "Application/Merge-Patch+JSON; charset=utf-8")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return bad request for patches that cannot be applied", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		for _, body := range []string{
+			`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "name": null}]}`,
+			`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "owner": "someone"}]}`,
+			`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "latitude": 91}]}`,
+			`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "latitude": "north"}]}`,
+			`{"locations": [{"name": "renamed"}]}`,
+			`{"locations": [{"id": "67d562e3d9f2d225ca4d9918"}]}`,
+			`{"locations": [{"id": "67d562e3d9f2d225ca4d9918", "expected_version": 2}]}`,
+			`{"locations": []}`,
+		} {
+			req := httptest.NewRequest(http.MethodPatch, "/locations", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+
+			res, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+			res.Body.Close()
+		}
+	})
+}
+
+func TestHandler_DeleteLocation(t *testing.T) {
+	t.Run("should delete location properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			DeleteLocation(&testDeleteLocationReq).
+			Return(&testDeleteLocationRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodDelete, "/location?id=67d562e3d9f2d225ca4d9918", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should pass the caller on for the history", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			DeleteLocation(&model.DeleteLocationRequest{ID: "67d562e3d9f2d225ca4d9918", Actor: "jane"}).
+			Return(&testDeleteLocationRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodDelete, "/location?id=67d562e3d9f2d225ca4d9918", http.NoBody)
+		req.Header.Set("X-Actor", "jane")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return not found error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			DeleteLocation(&testDeleteLocationReq).
+			Return(nil, mongo.ErrNoDocuments).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodDelete, "/location?id=67d562e3d9f2d225ca4d9918", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			DeleteLocation(&testDeleteLocationReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodDelete, "/location?id=67d562e3d9f2d225ca4d9918", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("should return bad request error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodDelete, "/location?id=test", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_DeleteLocations(t *testing.T) {
+	t.Run("should delete locations properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			DeleteLocations(&testDeleteLocationsReq).
+			Return(&testDeleteLocationsRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodDelete,
+			"/locations",
+			bytes.NewReader([]byte(`{"ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return partial content", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			DeleteLocations(&testDeleteLocationsReq).
+			Return(&model.DeleteLocationsResponse{
+				DeletedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				FailedIDs:    []string{"67d562e3d9f2d225ca4d9919"},
+				DeletedCount: 1,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodDelete,
+			"/locations",
+			bytes.NewReader([]byte(`{"ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			DeleteLocations(&testDeleteLocationsReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodDelete,
+			"/locations",
+			bytes.NewReader([]byte(`{"ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("should return bad request error when no ids are given", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodDelete, "/locations", bytes.NewReader([]byte(`{"ids": []}`)))
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_ArchiveLocations(t *testing.T) {
+	t.Run("should archive locations properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			ArchiveLocations(&testArchiveLocationsReq).
+			Return(&testArchiveLocationsRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations/archive",
+			bytes.NewReader([]byte(`{"ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return partial content", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			ArchiveLocations(&testArchiveLocationsReq).
+			Return(&model.ArchiveLocationsResponse{
+				ArchivedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				FailedIDs:     []string{"67d562e3d9f2d225ca4d9919"},
+				ArchivedCount: 1,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations/archive",
+			bytes.NewReader([]byte(`{"ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+	})
+
+	t.Run("should return bad request when ids are empty", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodPost, "/locations/archive", bytes.NewReader([]byte(`{"ids": []}`)))
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_RestoreLocations(t *testing.T) {
+	t.Run("should restore locations properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			RestoreLocations(&testRestoreLocationsReq).
+			Return(&testRestoreLocationsRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations/restore",
+			bytes.NewReader([]byte(`{"ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			RestoreLocations(&testRestoreLocationsReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations/restore",
+			bytes.NewReader([]byte(`{"ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+}
+
+func TestHandler_GetArchivedLocations(t *testing.T) {
+	t.Run("should get archived locations properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetArchivedLocations(&model.GetArchivedLocationsRequest{Page: 1, Limit: 10}).
+			Return(&model.GetArchivedLocationsResponse{Locations: []model.ArchivedLocation{}}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodGet, "/locations/archived?page=1&limit=10", http.NoBody)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
+func TestHandler_GetRoutes(t *testing.T) {
+	t.Run("should get routes properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetRoutes(&testGetRoutesReq).
+			Return(&testGetRoutesRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/routes?longitude=1.1&latitude=1.1",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return internal server error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetRoutes(&testGetRoutesReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/routes?longitude=1.1&latitude=1.1",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+	t.Run("should return bad request error", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/routes?longitude=test&latitude=1.1",
+			http.NoBody,
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should return bad request error when max distance is lower than min distance", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/routes?longitude=1.1&latitude=1.1&min_distance=10&max_distance=5",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should return bad request error when mode is unknown", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/routes?longitude=1.1&latitude=1.1&mode=shortest",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should pass tour mode to service", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetRoutes(&model.GetRoutesRequest{Latitude: float64Ptr(1.1), Longitude: float64Ptr(1.1), Mode: model.RouteModeTour}).
+			Return(&testGetRoutesRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/routes?longitude=1.1&latitude=1.1&mode=tour",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should pass limit and distance bounds to service", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetRoutes(&model.GetRoutesRequest{
+				Latitude:    float64Ptr(1.1),
+				Longitude:   float64Ptr(1.1),
+				Limit:       5,
+				MaxDistance: 20,
+				MinDistance: 1.5,
+			}).
+			Return(&testGetRoutesRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodGet,
+			"/routes?longitude=1.1&latitude=1.1&limit=5&max_distance=20&min_distance=1.5",
+			http.NoBody,
+		)
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
+func TestHandler_PlanRoute(t *testing.T) {
+	t.Run("should plan route properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			PlanRoute(&testPlanRouteReq).
+			Return(&testGetRoutesRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/routes",
+			bytes.NewReader([]byte(`{
+				"start": {"latitude": 1.1, "longitude": 1.1},
+				"return_to_start": true,
+				"location_ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]
+			}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should return partial content when some locations are missing", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			PlanRoute(&testPlanRouteReq).
+			Return(&model.GetRoutesResponse{
+				Routes:     testGetRoutesRes.Routes,
+				MissingIDs: []string{"67d562e3d9f2d225ca4d9919"},
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/routes",
+			bytes.NewReader([]byte(`{
+				"start": {"latitude": 1.1, "longitude": 1.1},
+				"return_to_start": true,
+				"location_ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]
+			}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+	})
+
 	t.Run("should return internal server error", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
@@ -51,50 +2296,222 @@ func TestHandler_CreateLocation(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			CreateLocation(&testCreateLocationReq).
-			Return(nil, assert.AnError).
+			PlanRoute(&testPlanRouteReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/routes",
+			bytes.NewReader([]byte(`{
+				"start": {"latitude": 1.1, "longitude": 1.1},
+				"return_to_start": true,
+				"location_ids": ["67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"]
+			}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	})
+
+	t.Run("should return bad request error when both end and return to start are set", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/routes",
+			bytes.NewReader([]byte(`{
+				"start": {"latitude": 1.1, "longitude": 1.1},
+				"end": {"latitude": 2.2, "longitude": 2.2},
+				"return_to_start": true,
+				"location_ids": ["67d562e3d9f2d225ca4d9918"]
+			}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should return bad request error when no location is given", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/routes",
+			bytes.NewReader([]byte(`{"start": {"latitude": 1.1, "longitude": 1.1}, "location_ids": []}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestHandler_GetDistanceMatrix(t *testing.T) {
+	matrixBody := `{
+		"origins": [{"latitude": 1.1, "longitude": 1.1}],
+		"destinations": [{"id": "67d562e3d9f2d225ca4d9918"}, {"latitude": 2.2, "longitude": 2.2}]
+	}`
+
+	t.Run("should get distance matrix properly", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetDistanceMatrix(&testDistanceMatrixReq).
+			Return(&testDistanceMatrixRes, nil).
+			Times(1)
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodPost, "/distance-matrix", bytes.NewReader([]byte(matrixBody)))
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("should stream large distance matrix", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			ResolveDistanceMatrix(&testDistanceMatrixReq).
+			Return(&model.DistanceMatrixResponse{
+				Origins:      testDistanceMatrixRes.Origins,
+				Destinations: testDistanceMatrixRes.Destinations,
+			}, nil).
+			Times(1)
+
+		handler := NewHandler(mockService, WithDistanceMatrixLimits(10, 1))
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodPost, "/distance-matrix", bytes.NewReader([]byte(matrixBody)))
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		var body model.DistanceMatrixResponse
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, testDistanceMatrixRes.Origins, body.Origins)
+		assert.Equal(t, testDistanceMatrixRes.Destinations, body.Destinations)
+
+		if assert.Len(t, body.Distances, 1) && assert.Len(t, body.Distances[0], 2) {
+			assert.InDelta(t, 0, body.Distances[0][0], 1e-9)
+			assert.InDelta(t, 172.9, body.Distances[0][1], 1.0)
+		}
+	})
+
+	t.Run("should return bad request error when matrix is too large", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService, WithDistanceMatrixLimits(1, 0))
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(http.MethodPost, "/distance-matrix", bytes.NewReader([]byte(matrixBody)))
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should return not found when locations are missing", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		mockService.
+			EXPECT().
+			GetDistanceMatrix(&testDistanceMatrixReq).
+			Return(&model.DistanceMatrixResponse{MissingIDs: []string{"67d562e3d9f2d225ca4d9918"}}, nil).
 			Times(1)
 
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
-		req := httptest.NewRequest(
-			http.MethodPost,
-			"/location",
-			bytes.NewReader([]byte(`{"name": "test", "latitude": 1.1, "longitude": 1.1, "marker_color": "FFFFFF"}`)),
-		)
+		req := httptest.NewRequest(http.MethodPost, "/distance-matrix", bytes.NewReader([]byte(matrixBody)))
 		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
 
 		assert.Nil(t, err)
-		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
 	})
 
-	t.Run("should return bad request error", func(t *testing.T) {
+	t.Run("should return internal server error", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
 		app := createTestApp()
 
+		mockService.
+			EXPECT().
+			GetDistanceMatrix(&testDistanceMatrixReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
-		req := httptest.NewRequest(
-			http.MethodPost,
-			"/location",
-			http.NoBody,
-		)
+		req := httptest.NewRequest(http.MethodPost, "/distance-matrix", bytes.NewReader([]byte(matrixBody)))
 		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
 
 		assert.Nil(t, err)
-		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
 	})
-	t.Run("should return validation error", func(t *testing.T) {
+
+	t.Run("should return bad request error when a point has both id and coordinates", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -105,8 +2522,11 @@ func TestHandler_CreateLocation(t *testing.T) {
 
 		req := httptest.NewRequest(
 			http.MethodPost,
-			"/location",
-			bytes.NewReader([]byte(`{"name": "test", "latitude": 1.1, "longitude": 1.1}`)),
+			"/distance-matrix",
+			bytes.NewReader([]byte(`{
+				"origins": [{"id": "67d562e3d9f2d225ca4d9918", "latitude": 1.1, "longitude": 1.1}],
+				"destinations": [{"latitude": 2.2, "longitude": 2.2}]
+			}`)),
 		)
 		req.Header.Set("Content-Type", "application/json")
 
@@ -118,8 +2538,8 @@ func TestHandler_CreateLocation(t *testing.T) {
 	})
 }
 
-func TestHandler_GetLocation(t *testing.T) {
-	t.Run("should get location properly", func(t *testing.T) {
+func TestHandler_GetNearbyLocations(t *testing.T) {
+	t.Run("should get nearby locations properly", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -127,8 +2547,8 @@ func TestHandler_GetLocation(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			GetLocation(&testGetLocationReq).
-			Return(&testGetLocationRes, nil).
+			GetNearbyLocations(&testGetNearbyLocationsReq).
+			Return(&testGetNearbyLocationsRes, nil).
 			Times(1)
 
 		handler := NewHandler(mockService)
@@ -136,10 +2556,9 @@ func TestHandler_GetLocation(t *testing.T) {
 
 		req := httptest.NewRequest(
 			http.MethodGet,
-			"/location?id=67d562e3d9f2d225ca4d9918",
+			"/locations/nearby?latitude=1.1&longitude=1.1&radius_km=10&page=1&limit=1",
 			http.NoBody,
 		)
-		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
@@ -156,7 +2575,7 @@ func TestHandler_GetLocation(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			GetLocation(&testGetLocationReq).
+			GetNearbyLocations(&testGetNearbyLocationsReq).
 			Return(nil, assert.AnError).
 			Times(1)
 
@@ -165,7 +2584,7 @@ func TestHandler_GetLocation(t *testing.T) {
 
 		req := httptest.NewRequest(
 			http.MethodGet,
-			"/location?id=67d562e3d9f2d225ca4d9918",
+			"/locations/nearby?latitude=1.1&longitude=1.1&radius_km=10&page=1&limit=1",
 			http.NoBody,
 		)
 
@@ -176,7 +2595,7 @@ func TestHandler_GetLocation(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
 	})
 
-	t.Run("should return bad request error", func(t *testing.T) {
+	t.Run("should return bad request error when radius is missing", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -187,10 +2606,9 @@ func TestHandler_GetLocation(t *testing.T) {
 
 		req := httptest.NewRequest(
 			http.MethodGet,
-			"/location?id=test",
+			"/locations/nearby?latitude=1.1&longitude=1.1",
 			http.NoBody,
 		)
-		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
@@ -200,8 +2618,8 @@ func TestHandler_GetLocation(t *testing.T) {
 	})
 }
 
-func TestHandler_GetLocations(t *testing.T) {
-	t.Run("should get location properly", func(t *testing.T) {
+func TestHandler_SearchLocations(t *testing.T) {
+	t.Run("should search locations properly", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -209,19 +2627,14 @@ func TestHandler_GetLocations(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			GetLocations(&testGetLocationsReq).
-			Return(&testGetLocationsRes, nil).
+			SearchLocations(&model.SearchLocationsRequest{Query: "galata", Latitude: float64Ptr(41), Longitude: float64Ptr(29)}).
+			Return(&model.SearchLocationsResponse{Locations: []model.LocationSearchResult{{ID: "1", Name: "Galata Tower", Score: 1}}}, nil).
 			Times(1)
 
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
-		req := httptest.NewRequest(
-			http.MethodGet,
-			"/locations?page=1&limit=1",
-			http.NoBody,
-		)
-		req.Header.Set("Content-Type", "application/json")
+		req := httptest.NewRequest(http.MethodGet, "/locations/search?q=galata&latitude=41&longitude=29", http.NoBody)
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
@@ -238,19 +2651,14 @@ func TestHandler_GetLocations(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			GetLocations(&testGetLocationsReq).
+			SearchLocations(&model.SearchLocationsRequest{Query: "galata"}).
 			Return(nil, assert.AnError).
 			Times(1)
 
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
-		req := httptest.NewRequest(
-			http.MethodGet,
-			"/locations?page=1&limit=1",
-			http.NoBody,
-		)
-		req.Header.Set("Content-Type", "application/json")
+		req := httptest.NewRequest(http.MethodGet, "/locations/search?q=galata", http.NoBody)
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
@@ -268,23 +2676,24 @@ func TestHandler_GetLocations(t *testing.T) {
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
-		req := httptest.NewRequest(
-			http.MethodGet,
-			"/locations?page=1&limit=test",
-			http.NoBody,
-		)
-		req.Header.Set("Content-Type", "application/json")
-
-		res, err := app.Test(req)
-		defer res.Body.Close()
-
-		assert.Nil(t, err)
-		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		for _, target := range []string{
+			"/locations/search",
+			"/locations/search?q=%20%20",
+			"/locations/search?q=galata&latitude=41",
+			"/locations/search?q=galata&limit=500",
+		} {
+			req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+
+			res, err := app.Test(req)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, target)
+			res.Body.Close()
+		}
 	})
 }
 
-func TestHandler_UpdateLocations(t *testing.T) {
-	t.Run("should update locations properly", func(t *testing.T) {
+func TestHandler_GetLocationsInBox(t *testing.T) {
+	t.Run("should get locations in box properly", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -292,38 +2701,18 @@ func TestHandler_UpdateLocations(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			UpdateLocations(&testUpdateLocationsReq).
-			Return(&testUpdateLocationsRes, nil).
+			GetLocationsInBox(&testGetLocationsInBoxReq).
+			Return(&testGetLocationsRes, nil).
 			Times(1)
 
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
-		reqBody := `{
-			"locations": [
-				{
-					"id": "67d562e3d9f2d225ca4d9918",
-					"name": "test",
-					"latitude": 1.1,
-					"longitude": 1.1,
-					"marker_color": "FFFFFF"
-				},
-				{
-					"id": "67d562e3d9f2d225ca4d9919",
-					"name": "test2",
-					"latitude": 2.2,
-					"longitude": 2.2,
-					"marker_color": "000000"
-				}
-			]
-		}`
-
 		req := httptest.NewRequest(
-			http.MethodPatch,
-			"/locations",
-			bytes.NewReader([]byte(reqBody)),
+			http.MethodGet,
+			"/locations/within-box?min_lat=0&min_lon=170&max_lat=2.2&max_lon=-170&page=1&limit=1",
+			http.NoBody,
 		)
-		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
@@ -332,7 +2721,7 @@ func TestHandler_UpdateLocations(t *testing.T) {
 		assert.Equal(t, http.StatusOK, res.StatusCode)
 	})
 
-	t.Run("should return partial content", func(t *testing.T) {
+	t.Run("should return internal server error", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -340,106 +2729,49 @@ func TestHandler_UpdateLocations(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			UpdateLocations(&testUpdateLocationsReqForPartialContent).
-			Return(&model.UpdateLocationsResponse{
-				UpdatedIDs:   []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
-				FailedIDs:    []string{"67d562e3d9ddd225ca4d9919"},
-				UpdatedCount: 2,
-			}, nil).
+			GetLocationsInBox(&testGetLocationsInBoxReq).
+			Return(nil, assert.AnError).
 			Times(1)
 
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
-		reqBody := `{
-			"locations": [
-				{
-					"id": "67d562e3d9f2d225ca4d9918",
-					"name": "test",
-					"latitude": 1.1,
-					"longitude": 1.1,
-					"marker_color": "FFFFFF"
-				},
-				{
-					"id": "67d562e3d9f2d225ca4d9919",
-					"name": "test2",
-					"latitude": 2.2,
-					"longitude": 2.2,
-					"marker_color": "000000"
-				},
-				{
-					"id": "67d562e3d9ddd225ca4d9919",
-					"name": "test3",
-					"latitude": 3.3,
-					"longitude": 3.3,
-					"marker_color": "000000"
-				}
-			]
-		}`
-
 		req := httptest.NewRequest(
-			http.MethodPatch,
-			"/locations",
-			bytes.NewReader([]byte(reqBody)),
+			http.MethodGet,
+			"/locations/within-box?min_lat=0&min_lon=170&max_lat=2.2&max_lon=-170&page=1&limit=1",
+			http.NoBody,
 		)
-		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
 
 		assert.Nil(t, err)
-		assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
 	})
 
-	t.Run("should return internal server error", func(t *testing.T) {
+	t.Run("should return bad request error when latitudes are swapped", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
 		app := createTestApp()
 
-		mockService.
-			EXPECT().
-			UpdateLocations(&testUpdateLocationsReq).
-			Return(nil, assert.AnError).
-			Times(1)
-
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
-		reqBody := `{
-			"locations": [
-				{
-					"id": "67d562e3d9f2d225ca4d9918",
-					"name": "test",
-					"latitude": 1.1,
-					"longitude": 1.1,
-					"marker_color": "FFFFFF"
-				},
-				{
-					"id": "67d562e3d9f2d225ca4d9919",
-					"name": "test2",
-					"latitude": 2.2,
-					"longitude": 2.2,	
-					"marker_color": "000000"
-				}
-			]
-		}`
-
 		req := httptest.NewRequest(
-			http.MethodPatch,
-			"/locations",
-			bytes.NewReader([]byte(reqBody)),
+			http.MethodGet,
+			"/locations/within-box?min_lat=10&min_lon=1&max_lat=5&max_lon=2",
+			http.NoBody,
 		)
-		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
 
 		assert.Nil(t, err)
-		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 	})
 
-	t.Run("should return bad request error when body is empty", func(t *testing.T) {
+	t.Run("should return bad request error when a corner is missing", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -449,11 +2781,10 @@ func TestHandler_UpdateLocations(t *testing.T) {
 		handler.RegisterRoutes(app)
 
 		req := httptest.NewRequest(
-			http.MethodPatch,
-			"/locations",
+			http.MethodGet,
+			"/locations/within-box?min_lat=1&min_lon=1&max_lat=5",
 			http.NoBody,
 		)
-		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
@@ -463,8 +2794,8 @@ func TestHandler_UpdateLocations(t *testing.T) {
 	})
 }
 
-func TestHandler_GetRoutes(t *testing.T) {
-	t.Run("should get routes properly", func(t *testing.T) {
+func TestHandler_GetLocationsInPolygon(t *testing.T) {
+	t.Run("should get locations in polygon properly", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -472,17 +2803,17 @@ func TestHandler_GetRoutes(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			GetRoutes(&testGetRoutesReq).
-			Return(&testGetRoutesRes, nil).
+			GetLocationsInPolygon(&testGetLocationsInPolygonReq).
+			Return(&testGetLocationsRes, nil).
 			Times(1)
 
 		handler := NewHandler(mockService)
 		handler.RegisterRoutes(app)
 
 		req := httptest.NewRequest(
-			http.MethodGet,
-			"/routes?longitude=1.1&latitude=1.1",
-			http.NoBody,
+			http.MethodPost,
+			"/locations/within-polygon?page=1&limit=1",
+			bytes.NewReader([]byte(`{"type": "Polygon", "coordinates": [[[0, 0], [2, 0], [2, 2], [0, 2], [0, 0]]]}`)),
 		)
 		req.Header.Set("Content-Type", "application/json")
 
@@ -501,7 +2832,7 @@ func TestHandler_GetRoutes(t *testing.T) {
 
 		mockService.
 			EXPECT().
-			GetRoutes(&testGetRoutesReq).
+			GetLocationsInPolygon(&testGetLocationsInPolygonReq).
 			Return(nil, assert.AnError).
 			Times(1)
 
@@ -509,10 +2840,11 @@ func TestHandler_GetRoutes(t *testing.T) {
 		handler.RegisterRoutes(app)
 
 		req := httptest.NewRequest(
-			http.MethodGet,
-			"/routes?longitude=1.1&latitude=1.1",
-			http.NoBody,
+			http.MethodPost,
+			"/locations/within-polygon?page=1&limit=1",
+			bytes.NewReader([]byte(`{"type": "Polygon", "coordinates": [[[0, 0], [2, 0], [2, 2], [0, 2], [0, 0]]]}`)),
 		)
+		req.Header.Set("Content-Type", "application/json")
 
 		res, err := app.Test(req)
 		defer res.Body.Close()
@@ -520,7 +2852,8 @@ func TestHandler_GetRoutes(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
 	})
-	t.Run("should return bad request error", func(t *testing.T) {
+
+	t.Run("should return bad request error when ring is not closed", func(t *testing.T) {
 		mockService, mockServiceController := createMockService(t)
 		defer mockServiceController.Finish()
 
@@ -530,9 +2863,32 @@ func TestHandler_GetRoutes(t *testing.T) {
 		handler.RegisterRoutes(app)
 
 		req := httptest.NewRequest(
-			http.MethodGet,
-			"/routes?longitude=test&latitude=1.1",
-			http.NoBody,
+			http.MethodPost,
+			"/locations/within-polygon",
+			bytes.NewReader([]byte(`{"type": "Polygon", "coordinates": [[[0, 0], [2, 0], [2, 2], [0, 2]]]}`)),
+		)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := app.Test(req)
+		defer res.Body.Close()
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("should return bad request error when geometry is not a polygon", func(t *testing.T) {
+		mockService, mockServiceController := createMockService(t)
+		defer mockServiceController.Finish()
+
+		app := createTestApp()
+
+		handler := NewHandler(mockService)
+		handler.RegisterRoutes(app)
+
+		req := httptest.NewRequest(
+			http.MethodPost,
+			"/locations/within-polygon",
+			bytes.NewReader([]byte(`{"type": "Point", "coordinates": [[[0, 0], [2, 0], [2, 2], [0, 0]]]}`)),
 		)
 		req.Header.Set("Content-Type", "application/json")
 
@@ -1,3 +1,5 @@
 db = db.getSiblingDB("location");
 
-db.createCollection("locations");
\ No newline at end of file
+db.createCollection("locations");
+
+db.locations.createIndex({ location: "2dsphere" });
\ No newline at end of file
@@ -0,0 +1,238 @@
+package helper
+
+const improvementEpsilon = 1e-9
+const maxOrOptSegment = 3
+
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Tour returns the order in which stops should be visited on an open path that
+// starts at start. The path is built with nearest-neighbour construction and then
+// improved with 2-opt and Or-opt moves until neither shortens it any further.
+// The returned slice holds indexes into stops.
+func Tour(start Point, stops []Point) []int {
+	return tour(start, nil, stops)
+}
+
+// TourToEnd works like Tour for a path that has to finish at end, which may be
+// the start itself for a round trip.
+func TourToEnd(start, end Point, stops []Point) []int {
+	return tour(start, &end, stops)
+}
+
+func tour(start Point, end *Point, stops []Point) []int {
+	if len(stops) == 0 {
+		return []int{}
+	}
+
+	points := make([]Point, 0, len(stops)+2)
+	points = append(points, start)
+	points = append(points, stops...)
+
+	// Node 0 is the start and nodes up to lastMovable are the stops. A fixed end
+	// is appended after them and never moves.
+	lastMovable := len(points) - 1
+	if end != nil {
+		points = append(points, *end)
+	}
+
+	dist := distanceMatrix(points)
+	path := nearestNeighbour(dist, lastMovable+1)
+
+	if end != nil {
+		path = append(path, lastMovable+1)
+	}
+
+	improved := true
+	for improved {
+		improved = twoOpt(path, dist, lastMovable)
+		improved = orOpt(path, dist, lastMovable) || improved
+	}
+
+	order := make([]int, 0, len(stops))
+	for _, node := range path[1 : lastMovable+1] {
+		order = append(order, node-1)
+	}
+
+	return order
+}
+
+// PathLength returns the length in kilometres of the path that visits points in order.
+func PathLength(points []Point) float64 {
+	var total float64
+
+	for i := 1; i < len(points); i++ {
+		total += Haversine(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+	}
+
+	return total
+}
+
+func distanceMatrix(points []Point) [][]float64 {
+	dist := make([][]float64, len(points))
+
+	for i := range points {
+		dist[i] = make([]float64, len(points))
+		for j := range points {
+			if i != j {
+				dist[i][j] = Haversine(points[i].Latitude, points[i].Longitude, points[j].Latitude, points[j].Longitude)
+			}
+		}
+	}
+
+	return dist
+}
+
+// nearestNeighbour starts at node 0 and repeatedly moves to the closest of the
+// first nodes nodes that has not been visited yet.
+func nearestNeighbour(dist [][]float64, nodes int) []int {
+	visited := make([]bool, nodes)
+	path := make([]int, 0, nodes+1)
+
+	current := 0
+	visited[current] = true
+	path = append(path, current)
+
+	for len(path) < nodes {
+		next := -1
+
+		for candidate := 0; candidate < nodes; candidate++ {
+			if visited[candidate] {
+				continue
+			}
+
+			if next == -1 || dist[current][candidate] < dist[current][next] {
+				next = candidate
+			}
+		}
+
+		visited[next] = true
+		path = append(path, next)
+		current = next
+	}
+
+	return path
+}
+
+// edge returns the length of the edge leaving position i of path, which is zero
+// past the end of an open path.
+func edge(path []int, dist [][]float64, i int) float64 {
+	if i+1 >= len(path) {
+		return 0
+	}
+
+	return dist[path[i]][path[i+1]]
+}
+
+// twoOpt reverses every segment whose reversal shortens the path in a single
+// sweep and reports whether the path changed. Position 0 is the fixed start and
+// positions after lastMovable hold the fixed end.
+func twoOpt(path []int, dist [][]float64, lastMovable int) bool {
+	improved := false
+
+	for i := 1; i < lastMovable; i++ {
+		for j := i + 1; j <= lastMovable; j++ {
+			before := dist[path[i-1]][path[i]] + edge(path, dist, j)
+
+			after := dist[path[i-1]][path[j]]
+			if j+1 < len(path) {
+				after += dist[path[i]][path[j+1]]
+			}
+
+			if after < before-improvementEpsilon {
+				reverse(path[i : j+1])
+				improved = true
+			}
+		}
+	}
+
+	return improved
+}
+
+// orOpt moves segments of up to maxOrOptSegment consecutive stops, optionally
+// reversed, between two other stops whenever that shortens the path, and
+// reports whether the path changed.
+func orOpt(path []int, dist [][]float64, lastMovable int) bool {
+	improved := false
+
+	for length := 1; length <= maxOrOptSegment; length++ {
+		for i := 1; i+length-1 <= lastMovable; i++ {
+			if relocateSegment(path, dist, i, i+length-1, lastMovable) {
+				improved = true
+			}
+		}
+	}
+
+	return improved
+}
+
+// relocateSegment moves path[i..j] to the first position found where it
+// shortens the path and reports whether it was moved.
+func relocateSegment(path []int, dist [][]float64, i, j, lastMovable int) bool {
+	first, end := path[i], path[j]
+
+	gain := dist[path[i-1]][first] + edge(path, dist, j)
+	if j+1 < len(path) {
+		gain -= dist[path[i-1]][path[j+1]]
+	}
+
+	for k := 0; k <= lastMovable; k++ {
+		if k >= i-1 && k <= j {
+			continue
+		}
+
+		var cost, reversedCost float64
+
+		if k+1 < len(path) {
+			cost = dist[path[k]][first] + dist[end][path[k+1]] - dist[path[k]][path[k+1]]
+			reversedCost = dist[path[k]][end] + dist[first][path[k+1]] - dist[path[k]][path[k+1]]
+		} else {
+			cost = dist[path[k]][first]
+			reversedCost = dist[path[k]][end]
+		}
+
+		switch {
+		case cost < gain-improvementEpsilon:
+			moveSegment(path, i, j, k, false)
+			return true
+		case reversedCost < gain-improvementEpsilon:
+			moveSegment(path, i, j, k, true)
+			return true
+		}
+	}
+
+	return false
+}
+
+// moveSegment moves path[i..j] so that it follows the node currently at
+// position k, reversing it when asked.
+func moveSegment(path []int, i, j, k int, reversed bool) {
+	segment := append([]int(nil), path[i:j+1]...)
+	if reversed {
+		reverse(segment)
+	}
+
+	rest := make([]int, 0, len(path)-len(segment))
+	rest = append(rest, path[:i]...)
+	rest = append(rest, path[j+1:]...)
+
+	insertAt := k + 1
+	if k > j {
+		insertAt = k - len(segment) + 1
+	}
+
+	result := make([]int, 0, len(path))
+	result = append(result, rest[:insertAt]...)
+	result = append(result, segment...)
+	result = append(result, rest[insertAt:]...)
+
+	copy(path, result)
+}
+
+func reverse(nodes []int) {
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+}
@@ -0,0 +1,106 @@
+package helper
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTour(t *testing.T) {
+	t.Run("should return empty order without stops", func(t *testing.T) {
+		assert.Empty(t, Tour(Point{}, nil))
+	})
+
+	t.Run("should visit stops on a line in order", func(t *testing.T) {
+		stops := []Point{
+			{Latitude: 0, Longitude: 3},
+			{Latitude: 0, Longitude: 1},
+			{Latitude: 0, Longitude: 2},
+		}
+
+		assert.Equal(t, []int{1, 2, 0}, Tour(Point{}, stops))
+	})
+
+	t.Run("should not be longer than nearest neighbour", func(t *testing.T) {
+		random := rand.New(rand.NewSource(42))
+
+		start := Point{Latitude: 41, Longitude: 29}
+		stops := make([]Point, 60)
+
+		for i := range stops {
+			stops[i] = Point{Latitude: 40 + random.Float64()*2, Longitude: 28 + random.Float64()*2}
+		}
+
+		order := Tour(start, stops)
+
+		sorted := append([]int(nil), order...)
+		sort.Ints(sorted)
+
+		for i := range sorted {
+			assert.Equal(t, i, sorted[i])
+		}
+
+		points := append([]Point{start}, stops...)
+		greedy := nearestNeighbour(distanceMatrix(points), len(points))
+
+		greedyPath := make([]Point, 0, len(points))
+		for _, node := range greedy {
+			greedyPath = append(greedyPath, points[node])
+		}
+
+		tourPath := []Point{start}
+		for _, index := range order {
+			tourPath = append(tourPath, stops[index])
+		}
+
+		assert.LessOrEqual(t, PathLength(tourPath), PathLength(greedyPath))
+	})
+}
+
+func TestTourToEnd(t *testing.T) {
+	t.Run("should finish next to the fixed end", func(t *testing.T) {
+		stops := []Point{
+			{Latitude: 0, Longitude: 1},
+			{Latitude: 0, Longitude: 2},
+			{Latitude: 0, Longitude: 3},
+		}
+
+		end := Point{Latitude: 0, Longitude: 4}
+
+		assert.Equal(t, []int{0, 1, 2}, TourToEnd(Point{}, end, stops))
+	})
+
+	t.Run("should come back to start on a round trip", func(t *testing.T) {
+		stops := []Point{
+			{Latitude: 1, Longitude: 1},
+			{Latitude: -1, Longitude: 1},
+			{Latitude: 1, Longitude: -1},
+			{Latitude: -1, Longitude: -1},
+		}
+
+		order := TourToEnd(Point{}, Point{}, stops)
+
+		path := []Point{{}}
+		for _, index := range order {
+			path = append(path, stops[index])
+		}
+
+		path = append(path, Point{})
+
+		// The shortest round trip walks around the square instead of crossing it.
+		assert.InDelta(t, PathLength([]Point{{}, stops[0], stops[2], stops[3], stops[1], {}}), PathLength(path), 1e-6)
+	})
+}
+
+func TestPathLength(t *testing.T) {
+	points := []Point{
+		{Latitude: 41.0082, Longitude: 28.9784},
+		{Latitude: 39.9334, Longitude: 32.8597},
+		{Latitude: 41.0082, Longitude: 28.9784},
+	}
+
+	assert.InDelta(t, 702.0, PathLength(points), 10.0)
+	assert.Zero(t, PathLength(points[:1]))
+}
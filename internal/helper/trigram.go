@@ -0,0 +1,48 @@
+package helper
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Trigrams splits s into lower-case words and returns the set of three letter
+// sequences of each word, padded with two spaces in front and one behind so that
+// the start of a word weighs more than its end. Anything that is not a letter or
+// a digit separates words.
+func Trigrams(s string) map[string]struct{} {
+	trigrams := make(map[string]struct{})
+
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+
+		for i := 0; i+3 <= len(padded); i++ {
+			trigrams[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+
+	return trigrams
+}
+
+// TrigramSimilarity returns how alike a and b are as the share of trigrams they
+// have in common, from 0 for nothing in common to 1 for the same words. A typo
+// only changes the few trigrams around it, so misspelt names still score well.
+func TrigramSimilarity(a, b string) float64 {
+	left, right := Trigrams(a), Trigrams(b)
+	if len(left) == 0 || len(right) == 0 {
+		return 0
+	}
+
+	shared := 0
+
+	for trigram := range left {
+		if _, ok := right[trigram]; ok {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(left)+len(right)-shared)
+}
@@ -0,0 +1,35 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrigrams(t *testing.T) {
+	assert.Equal(t, map[string]struct{}{
+		"  c": {}, " ca": {}, "cat": {}, "at ": {},
+		"  o": {}, " ok": {}, "ok ": {},
+	}, Trigrams("Cat, OK"))
+
+	assert.Empty(t, Trigrams(" -- "))
+}
+
+func TestTrigramSimilarity(t *testing.T) {
+	t.Run("should be one for the same words in any case", func(t *testing.T) {
+		assert.Equal(t, 1.0, TrigramSimilarity("Galata Tower", "galata tower"))
+	})
+
+	t.Run("should be zero without anything in common", func(t *testing.T) {
+		assert.Equal(t, 0.0, TrigramSimilarity("Galata", "Moda"))
+		assert.Equal(t, 0.0, TrigramSimilarity("", "Moda"))
+	})
+
+	t.Run("should score a typo above an unrelated name", func(t *testing.T) {
+		typo := TrigramSimilarity("galta tower", "Galata Tower")
+		other := TrigramSimilarity("galta tower", "Maiden's Tower")
+
+		assert.Greater(t, typo, 0.4)
+		assert.Greater(t, typo, other)
+	})
+}
@@ -10,6 +10,7 @@
 package internal
 
 import (
+	io "io"
 	model "location-api/model"
 	reflect "reflect"
 
@@ -39,6 +40,21 @@ func (m *Mockactions) EXPECT() *MockactionsMockRecorder {
 	return m.recorder
 }
 
+// ArchiveLocations mocks base method.
+func (m *Mockactions) ArchiveLocations(req *model.ArchiveLocationsRequest) (*model.ArchiveLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveLocations", req)
+	ret0, _ := ret[0].(*model.ArchiveLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveLocations indicates an expected call of ArchiveLocations.
+func (mr *MockactionsMockRecorder) ArchiveLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveLocations", reflect.TypeOf((*Mockactions)(nil).ArchiveLocations), req)
+}
+
 // CreateLocation mocks base method.
 func (m *Mockactions) CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error) {
 	m.ctrl.T.Helper()
@@ -54,6 +70,110 @@ func (mr *MockactionsMockRecorder) CreateLocation(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocation", reflect.TypeOf((*Mockactions)(nil).CreateLocation), req)
 }
 
+// CreateLocations mocks base method.
+func (m *Mockactions) CreateLocations(req *model.CreateLocationsRequest) (*model.CreateLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLocations", req)
+	ret0, _ := ret[0].(*model.CreateLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLocations indicates an expected call of CreateLocations.
+func (mr *MockactionsMockRecorder) CreateLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocations", reflect.TypeOf((*Mockactions)(nil).CreateLocations), req)
+}
+
+// CreateLocationsFromGeoJSON mocks base method.
+func (m *Mockactions) CreateLocationsFromGeoJSON(req *model.ImportGeoJSONRequest) (*model.CreateLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLocationsFromGeoJSON", req)
+	ret0, _ := ret[0].(*model.CreateLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLocationsFromGeoJSON indicates an expected call of CreateLocationsFromGeoJSON.
+func (mr *MockactionsMockRecorder) CreateLocationsFromGeoJSON(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocationsFromGeoJSON", reflect.TypeOf((*Mockactions)(nil).CreateLocationsFromGeoJSON), req)
+}
+
+// DeleteLocation mocks base method.
+func (m *Mockactions) DeleteLocation(req *model.DeleteLocationRequest) (*model.DeleteLocationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLocation", req)
+	ret0, _ := ret[0].(*model.DeleteLocationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLocation indicates an expected call of DeleteLocation.
+func (mr *MockactionsMockRecorder) DeleteLocation(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLocation", reflect.TypeOf((*Mockactions)(nil).DeleteLocation), req)
+}
+
+// DeleteLocations mocks base method.
+func (m *Mockactions) DeleteLocations(req *model.DeleteLocationsRequest) (*model.DeleteLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLocations", req)
+	ret0, _ := ret[0].(*model.DeleteLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLocations indicates an expected call of DeleteLocations.
+func (mr *MockactionsMockRecorder) DeleteLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLocations", reflect.TypeOf((*Mockactions)(nil).DeleteLocations), req)
+}
+
+// ExportLocations mocks base method.
+func (m *Mockactions) ExportLocations(req *model.ExportLocationsRequest, w io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportLocations", req, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportLocations indicates an expected call of ExportLocations.
+func (mr *MockactionsMockRecorder) ExportLocations(req, w any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportLocations", reflect.TypeOf((*Mockactions)(nil).ExportLocations), req, w)
+}
+
+// GetArchivedLocations mocks base method.
+func (m *Mockactions) GetArchivedLocations(req *model.GetArchivedLocationsRequest) (*model.GetArchivedLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArchivedLocations", req)
+	ret0, _ := ret[0].(*model.GetArchivedLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArchivedLocations indicates an expected call of GetArchivedLocations.
+func (mr *MockactionsMockRecorder) GetArchivedLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArchivedLocations", reflect.TypeOf((*Mockactions)(nil).GetArchivedLocations), req)
+}
+
+// GetDistanceMatrix mocks base method.
+func (m *Mockactions) GetDistanceMatrix(req *model.DistanceMatrixRequest) (*model.DistanceMatrixResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDistanceMatrix", req)
+	ret0, _ := ret[0].(*model.DistanceMatrixResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDistanceMatrix indicates an expected call of GetDistanceMatrix.
+func (mr *MockactionsMockRecorder) GetDistanceMatrix(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDistanceMatrix", reflect.TypeOf((*Mockactions)(nil).GetDistanceMatrix), req)
+}
+
 // GetLocation mocks base method.
 func (m *Mockactions) GetLocation(req *model.GetLocationRequest) (*model.GetLocationResponse, error) {
 	m.ctrl.T.Helper()
@@ -69,6 +189,21 @@ func (mr *MockactionsMockRecorder) GetLocation(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocation", reflect.TypeOf((*Mockactions)(nil).GetLocation), req)
 }
 
+// GetLocationHistory mocks base method.
+func (m *Mockactions) GetLocationHistory(req *model.GetLocationHistoryRequest) (*model.GetLocationHistoryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationHistory", req)
+	ret0, _ := ret[0].(*model.GetLocationHistoryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationHistory indicates an expected call of GetLocationHistory.
+func (mr *MockactionsMockRecorder) GetLocationHistory(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationHistory", reflect.TypeOf((*Mockactions)(nil).GetLocationHistory), req)
+}
+
 // GetLocations mocks base method.
 func (m *Mockactions) GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error) {
 	m.ctrl.T.Helper()
@@ -84,6 +219,51 @@ func (mr *MockactionsMockRecorder) GetLocations(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocations", reflect.TypeOf((*Mockactions)(nil).GetLocations), req)
 }
 
+// GetLocationsInBox mocks base method.
+func (m *Mockactions) GetLocationsInBox(req *model.GetLocationsInBoxRequest) (*model.GetLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationsInBox", req)
+	ret0, _ := ret[0].(*model.GetLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationsInBox indicates an expected call of GetLocationsInBox.
+func (mr *MockactionsMockRecorder) GetLocationsInBox(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationsInBox", reflect.TypeOf((*Mockactions)(nil).GetLocationsInBox), req)
+}
+
+// GetLocationsInPolygon mocks base method.
+func (m *Mockactions) GetLocationsInPolygon(req *model.GetLocationsInPolygonRequest) (*model.GetLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationsInPolygon", req)
+	ret0, _ := ret[0].(*model.GetLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationsInPolygon indicates an expected call of GetLocationsInPolygon.
+func (mr *MockactionsMockRecorder) GetLocationsInPolygon(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationsInPolygon", reflect.TypeOf((*Mockactions)(nil).GetLocationsInPolygon), req)
+}
+
+// GetNearbyLocations mocks base method.
+func (m *Mockactions) GetNearbyLocations(req *model.GetNearbyLocationsRequest) (*model.GetNearbyLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNearbyLocations", req)
+	ret0, _ := ret[0].(*model.GetNearbyLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNearbyLocations indicates an expected call of GetNearbyLocations.
+func (mr *MockactionsMockRecorder) GetNearbyLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNearbyLocations", reflect.TypeOf((*Mockactions)(nil).GetNearbyLocations), req)
+}
+
 // GetRoutes mocks base method.
 func (m *Mockactions) GetRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error) {
 	m.ctrl.T.Helper()
@@ -99,6 +279,111 @@ func (mr *MockactionsMockRecorder) GetRoutes(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoutes", reflect.TypeOf((*Mockactions)(nil).GetRoutes), req)
 }
 
+// ImportLocations mocks base method.
+func (m *Mockactions) ImportLocations(req *model.ImportLocationsRequest, data io.Reader) (*model.ImportLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportLocations", req, data)
+	ret0, _ := ret[0].(*model.ImportLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportLocations indicates an expected call of ImportLocations.
+func (mr *MockactionsMockRecorder) ImportLocations(req, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportLocations", reflect.TypeOf((*Mockactions)(nil).ImportLocations), req, data)
+}
+
+// PatchLocations mocks base method.
+func (m *Mockactions) PatchLocations(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchLocations", req)
+	ret0, _ := ret[0].(*model.UpdateLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchLocations indicates an expected call of PatchLocations.
+func (mr *MockactionsMockRecorder) PatchLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchLocations", reflect.TypeOf((*Mockactions)(nil).PatchLocations), req)
+}
+
+// PlanRoute mocks base method.
+func (m *Mockactions) PlanRoute(req *model.PlanRouteRequest) (*model.GetRoutesResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlanRoute", req)
+	ret0, _ := ret[0].(*model.GetRoutesResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlanRoute indicates an expected call of PlanRoute.
+func (mr *MockactionsMockRecorder) PlanRoute(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlanRoute", reflect.TypeOf((*Mockactions)(nil).PlanRoute), req)
+}
+
+// ResolveDistanceMatrix mocks base method.
+func (m *Mockactions) ResolveDistanceMatrix(req *model.DistanceMatrixRequest) (*model.DistanceMatrixResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveDistanceMatrix", req)
+	ret0, _ := ret[0].(*model.DistanceMatrixResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveDistanceMatrix indicates an expected call of ResolveDistanceMatrix.
+func (mr *MockactionsMockRecorder) ResolveDistanceMatrix(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveDistanceMatrix", reflect.TypeOf((*Mockactions)(nil).ResolveDistanceMatrix), req)
+}
+
+// RestoreLocations mocks base method.
+func (m *Mockactions) RestoreLocations(req *model.RestoreLocationsRequest) (*model.RestoreLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreLocations", req)
+	ret0, _ := ret[0].(*model.RestoreLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreLocations indicates an expected call of RestoreLocations.
+func (mr *MockactionsMockRecorder) RestoreLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreLocations", reflect.TypeOf((*Mockactions)(nil).RestoreLocations), req)
+}
+
+// RevertLocation mocks base method.
+func (m *Mockactions) RevertLocation(req *model.RevertLocationRequest) (*model.GetLocationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertLocation", req)
+	ret0, _ := ret[0].(*model.GetLocationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevertLocation indicates an expected call of RevertLocation.
+func (mr *MockactionsMockRecorder) RevertLocation(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertLocation", reflect.TypeOf((*Mockactions)(nil).RevertLocation), req)
+}
+
+// SearchLocations mocks base method.
+func (m *Mockactions) SearchLocations(req *model.SearchLocationsRequest) (*model.SearchLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchLocations", req)
+	ret0, _ := ret[0].(*model.SearchLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchLocations indicates an expected call of SearchLocations.
+func (mr *MockactionsMockRecorder) SearchLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchLocations", reflect.TypeOf((*Mockactions)(nil).SearchLocations), req)
+}
+
 // UpdateLocations mocks base method.
 func (m *Mockactions) UpdateLocations(req *model.UpdateLocationsRequest) (*model.UpdateLocationsResponse, error) {
 	m.ctrl.T.Helper()
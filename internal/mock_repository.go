@@ -12,6 +12,7 @@ package internal
 import (
 	model "location-api/model"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +40,21 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// ArchiveLocations mocks base method.
+func (m *MockStore) ArchiveLocations(req *model.ArchiveLocationsRequest) (*model.ArchiveLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveLocations", req)
+	ret0, _ := ret[0].(*model.ArchiveLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveLocations indicates an expected call of ArchiveLocations.
+func (mr *MockStoreMockRecorder) ArchiveLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveLocations", reflect.TypeOf((*MockStore)(nil).ArchiveLocations), req)
+}
+
 // CreateLocation mocks base method.
 func (m *MockStore) CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error) {
 	m.ctrl.T.Helper()
@@ -54,6 +70,81 @@ func (mr *MockStoreMockRecorder) CreateLocation(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocation", reflect.TypeOf((*MockStore)(nil).CreateLocation), req)
 }
 
+// CreateLocations mocks base method.
+func (m *MockStore) CreateLocations(req *model.CreateLocationsRequest) (*model.CreateLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLocations", req)
+	ret0, _ := ret[0].(*model.CreateLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLocations indicates an expected call of CreateLocations.
+func (mr *MockStoreMockRecorder) CreateLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocations", reflect.TypeOf((*MockStore)(nil).CreateLocations), req)
+}
+
+// DeleteLocation mocks base method.
+func (m *MockStore) DeleteLocation(req *model.DeleteLocationRequest) (*model.DeleteLocationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLocation", req)
+	ret0, _ := ret[0].(*model.DeleteLocationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLocation indicates an expected call of DeleteLocation.
+func (mr *MockStoreMockRecorder) DeleteLocation(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLocation", reflect.TypeOf((*MockStore)(nil).DeleteLocation), req)
+}
+
+// DeleteLocations mocks base method.
+func (m *MockStore) DeleteLocations(req *model.DeleteLocationsRequest) (*model.DeleteLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLocations", req)
+	ret0, _ := ret[0].(*model.DeleteLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLocations indicates an expected call of DeleteLocations.
+func (mr *MockStoreMockRecorder) DeleteLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLocations", reflect.TypeOf((*MockStore)(nil).DeleteLocations), req)
+}
+
+// FuzzySearchLocations mocks base method.
+func (m *MockStore) FuzzySearchLocations(query string, limit int64) ([]model.LocationSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FuzzySearchLocations", query, limit)
+	ret0, _ := ret[0].([]model.LocationSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FuzzySearchLocations indicates an expected call of FuzzySearchLocations.
+func (mr *MockStoreMockRecorder) FuzzySearchLocations(query, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FuzzySearchLocations", reflect.TypeOf((*MockStore)(nil).FuzzySearchLocations), query, limit)
+}
+
+// GetArchivedLocations mocks base method.
+func (m *MockStore) GetArchivedLocations(req *model.GetArchivedLocationsRequest) (*model.GetArchivedLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArchivedLocations", req)
+	ret0, _ := ret[0].(*model.GetArchivedLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArchivedLocations indicates an expected call of GetArchivedLocations.
+func (mr *MockStoreMockRecorder) GetArchivedLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArchivedLocations", reflect.TypeOf((*MockStore)(nil).GetArchivedLocations), req)
+}
+
 // GetLocation mocks base method.
 func (m *MockStore) GetLocation(req *model.GetLocationRequest) (*model.GetLocationResponse, error) {
 	m.ctrl.T.Helper()
@@ -69,6 +160,36 @@ func (mr *MockStoreMockRecorder) GetLocation(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocation", reflect.TypeOf((*MockStore)(nil).GetLocation), req)
 }
 
+// GetLocationAsOf mocks base method.
+func (m *MockStore) GetLocationAsOf(id string, asOf time.Time) (*model.GetLocationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationAsOf", id, asOf)
+	ret0, _ := ret[0].(*model.GetLocationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationAsOf indicates an expected call of GetLocationAsOf.
+func (mr *MockStoreMockRecorder) GetLocationAsOf(id, asOf any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationAsOf", reflect.TypeOf((*MockStore)(nil).GetLocationAsOf), id, asOf)
+}
+
+// GetLocationHistory mocks base method.
+func (m *MockStore) GetLocationHistory(req *model.GetLocationHistoryRequest) (*model.GetLocationHistoryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationHistory", req)
+	ret0, _ := ret[0].(*model.GetLocationHistoryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationHistory indicates an expected call of GetLocationHistory.
+func (mr *MockStoreMockRecorder) GetLocationHistory(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationHistory", reflect.TypeOf((*MockStore)(nil).GetLocationHistory), req)
+}
+
 // GetLocations mocks base method.
 func (m *MockStore) GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error) {
 	m.ctrl.T.Helper()
@@ -84,19 +205,168 @@ func (mr *MockStoreMockRecorder) GetLocations(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocations", reflect.TypeOf((*MockStore)(nil).GetLocations), req)
 }
 
+// GetLocationsByIDs mocks base method.
+func (m *MockStore) GetLocationsByIDs(ids []string) (*model.GetLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationsByIDs", ids)
+	ret0, _ := ret[0].(*model.GetLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationsByIDs indicates an expected call of GetLocationsByIDs.
+func (mr *MockStoreMockRecorder) GetLocationsByIDs(ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationsByIDs", reflect.TypeOf((*MockStore)(nil).GetLocationsByIDs), ids)
+}
+
+// GetLocationsInBox mocks base method.
+func (m *MockStore) GetLocationsInBox(req *model.GetLocationsInBoxRequest) (*model.GetLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationsInBox", req)
+	ret0, _ := ret[0].(*model.GetLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationsInBox indicates an expected call of GetLocationsInBox.
+func (mr *MockStoreMockRecorder) GetLocationsInBox(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationsInBox", reflect.TypeOf((*MockStore)(nil).GetLocationsInBox), req)
+}
+
+// GetLocationsInPolygon mocks base method.
+func (m *MockStore) GetLocationsInPolygon(req *model.GetLocationsInPolygonRequest) (*model.GetLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationsInPolygon", req)
+	ret0, _ := ret[0].(*model.GetLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationsInPolygon indicates an expected call of GetLocationsInPolygon.
+func (mr *MockStoreMockRecorder) GetLocationsInPolygon(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationsInPolygon", reflect.TypeOf((*MockStore)(nil).GetLocationsInPolygon), req)
+}
+
+// GetNearbyLocations mocks base method.
+func (m *MockStore) GetNearbyLocations(req *model.GetNearbyLocationsRequest) (*model.GetNearbyLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNearbyLocations", req)
+	ret0, _ := ret[0].(*model.GetNearbyLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNearbyLocations indicates an expected call of GetNearbyLocations.
+func (mr *MockStoreMockRecorder) GetNearbyLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNearbyLocations", reflect.TypeOf((*MockStore)(nil).GetNearbyLocations), req)
+}
+
 // GetRoutes mocks base method.
-func (m *MockStore) GetRoutes() (*model.GetAllLocationsDBResponse, error) {
+func (m *MockStore) GetRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRoutes")
-	ret0, _ := ret[0].(*model.GetAllLocationsDBResponse)
+	ret := m.ctrl.Call(m, "GetRoutes", req)
+	ret0, _ := ret[0].(*model.GetRoutesResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetRoutes indicates an expected call of GetRoutes.
-func (mr *MockStoreMockRecorder) GetRoutes() *gomock.Call {
+func (mr *MockStoreMockRecorder) GetRoutes(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoutes", reflect.TypeOf((*MockStore)(nil).GetRoutes), req)
+}
+
+// PatchLocations mocks base method.
+func (m *MockStore) PatchLocations(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchLocations", req)
+	ret0, _ := ret[0].(*model.UpdateLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchLocations indicates an expected call of PatchLocations.
+func (mr *MockStoreMockRecorder) PatchLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchLocations", reflect.TypeOf((*MockStore)(nil).PatchLocations), req)
+}
+
+// PurgeArchivedLocations mocks base method.
+func (m *MockStore) PurgeArchivedLocations(archivedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeArchivedLocations", archivedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeArchivedLocations indicates an expected call of PurgeArchivedLocations.
+func (mr *MockStoreMockRecorder) PurgeArchivedLocations(archivedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeArchivedLocations", reflect.TypeOf((*MockStore)(nil).PurgeArchivedLocations), archivedBefore)
+}
+
+// RestoreLocations mocks base method.
+func (m *MockStore) RestoreLocations(req *model.RestoreLocationsRequest) (*model.RestoreLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreLocations", req)
+	ret0, _ := ret[0].(*model.RestoreLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreLocations indicates an expected call of RestoreLocations.
+func (mr *MockStoreMockRecorder) RestoreLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreLocations", reflect.TypeOf((*MockStore)(nil).RestoreLocations), req)
+}
+
+// RevertLocation mocks base method.
+func (m *MockStore) RevertLocation(req *model.RevertLocationRequest) (*model.GetLocationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertLocation", req)
+	ret0, _ := ret[0].(*model.GetLocationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevertLocation indicates an expected call of RevertLocation.
+func (mr *MockStoreMockRecorder) RevertLocation(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertLocation", reflect.TypeOf((*MockStore)(nil).RevertLocation), req)
+}
+
+// StreamLocations mocks base method.
+func (m *MockStore) StreamLocations(fn func(*model.GetLocationResponse) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamLocations", fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamLocations indicates an expected call of StreamLocations.
+func (mr *MockStoreMockRecorder) StreamLocations(fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamLocations", reflect.TypeOf((*MockStore)(nil).StreamLocations), fn)
+}
+
+// TextSearchLocations mocks base method.
+func (m *MockStore) TextSearchLocations(query string, limit int64) ([]model.LocationSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TextSearchLocations", query, limit)
+	ret0, _ := ret[0].([]model.LocationSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TextSearchLocations indicates an expected call of TextSearchLocations.
+func (mr *MockStoreMockRecorder) TextSearchLocations(query, limit any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoutes", reflect.TypeOf((*MockStore)(nil).GetRoutes))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TextSearchLocations", reflect.TypeOf((*MockStore)(nil).TextSearchLocations), query, limit)
 }
 
 // UpdateLocations mocks base method.
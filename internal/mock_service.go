@@ -12,6 +12,7 @@ package internal
 import (
 	model "location-api/model"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +40,21 @@ func (m *MockLocationDBStore) EXPECT() *MockLocationDBStoreMockRecorder {
 	return m.recorder
 }
 
+// ArchiveLocations mocks base method.
+func (m *MockLocationDBStore) ArchiveLocations(req *model.ArchiveLocationsRequest) (*model.ArchiveLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchiveLocations", req)
+	ret0, _ := ret[0].(*model.ArchiveLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchiveLocations indicates an expected call of ArchiveLocations.
+func (mr *MockLocationDBStoreMockRecorder) ArchiveLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchiveLocations", reflect.TypeOf((*MockLocationDBStore)(nil).ArchiveLocations), req)
+}
+
 // CreateLocation mocks base method.
 func (m *MockLocationDBStore) CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error) {
 	m.ctrl.T.Helper()
@@ -54,6 +70,81 @@ func (mr *MockLocationDBStoreMockRecorder) CreateLocation(req any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocation", reflect.TypeOf((*MockLocationDBStore)(nil).CreateLocation), req)
 }
 
+// CreateLocations mocks base method.
+func (m *MockLocationDBStore) CreateLocations(req *model.CreateLocationsRequest) (*model.CreateLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLocations", req)
+	ret0, _ := ret[0].(*model.CreateLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLocations indicates an expected call of CreateLocations.
+func (mr *MockLocationDBStoreMockRecorder) CreateLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocations", reflect.TypeOf((*MockLocationDBStore)(nil).CreateLocations), req)
+}
+
+// DeleteLocation mocks base method.
+func (m *MockLocationDBStore) DeleteLocation(req *model.DeleteLocationRequest) (*model.DeleteLocationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLocation", req)
+	ret0, _ := ret[0].(*model.DeleteLocationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLocation indicates an expected call of DeleteLocation.
+func (mr *MockLocationDBStoreMockRecorder) DeleteLocation(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLocation", reflect.TypeOf((*MockLocationDBStore)(nil).DeleteLocation), req)
+}
+
+// DeleteLocations mocks base method.
+func (m *MockLocationDBStore) DeleteLocations(req *model.DeleteLocationsRequest) (*model.DeleteLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLocations", req)
+	ret0, _ := ret[0].(*model.DeleteLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLocations indicates an expected call of DeleteLocations.
+func (mr *MockLocationDBStoreMockRecorder) DeleteLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLocations", reflect.TypeOf((*MockLocationDBStore)(nil).DeleteLocations), req)
+}
+
+// FuzzySearchLocations mocks base method.
+func (m *MockLocationDBStore) FuzzySearchLocations(query string, limit int64) ([]model.LocationSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FuzzySearchLocations", query, limit)
+	ret0, _ := ret[0].([]model.LocationSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FuzzySearchLocations indicates an expected call of FuzzySearchLocations.
+func (mr *MockLocationDBStoreMockRecorder) FuzzySearchLocations(query, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FuzzySearchLocations", reflect.TypeOf((*MockLocationDBStore)(nil).FuzzySearchLocations), query, limit)
+}
+
+// GetArchivedLocations mocks base method.
+func (m *MockLocationDBStore) GetArchivedLocations(req *model.GetArchivedLocationsRequest) (*model.GetArchivedLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArchivedLocations", req)
+	ret0, _ := ret[0].(*model.GetArchivedLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArchivedLocations indicates an expected call of GetArchivedLocations.
+func (mr *MockLocationDBStoreMockRecorder) GetArchivedLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArchivedLocations", reflect.TypeOf((*MockLocationDBStore)(nil).GetArchivedLocations), req)
+}
+
 // GetLocation mocks base method.
 func (m *MockLocationDBStore) GetLocation(req *model.GetLocationRequest) (*model.GetLocationResponse, error) {
 	m.ctrl.T.Helper()
@@ -69,6 +160,36 @@ func (mr *MockLocationDBStoreMockRecorder) GetLocation(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocation", reflect.TypeOf((*MockLocationDBStore)(nil).GetLocation), req)
 }
 
+// GetLocationAsOf mocks base method.
+func (m *MockLocationDBStore) GetLocationAsOf(id string, asOf time.Time) (*model.GetLocationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationAsOf", id, asOf)
+	ret0, _ := ret[0].(*model.GetLocationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationAsOf indicates an expected call of GetLocationAsOf.
+func (mr *MockLocationDBStoreMockRecorder) GetLocationAsOf(id, asOf any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationAsOf", reflect.TypeOf((*MockLocationDBStore)(nil).GetLocationAsOf), id, asOf)
+}
+
+// GetLocationHistory mocks base method.
+func (m *MockLocationDBStore) GetLocationHistory(req *model.GetLocationHistoryRequest) (*model.GetLocationHistoryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationHistory", req)
+	ret0, _ := ret[0].(*model.GetLocationHistoryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationHistory indicates an expected call of GetLocationHistory.
+func (mr *MockLocationDBStoreMockRecorder) GetLocationHistory(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationHistory", reflect.TypeOf((*MockLocationDBStore)(nil).GetLocationHistory), req)
+}
+
 // GetLocations mocks base method.
 func (m *MockLocationDBStore) GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error) {
 	m.ctrl.T.Helper()
@@ -84,19 +205,168 @@ func (mr *MockLocationDBStoreMockRecorder) GetLocations(req any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocations", reflect.TypeOf((*MockLocationDBStore)(nil).GetLocations), req)
 }
 
+// GetLocationsByIDs mocks base method.
+func (m *MockLocationDBStore) GetLocationsByIDs(ids []string) (*model.GetLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationsByIDs", ids)
+	ret0, _ := ret[0].(*model.GetLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationsByIDs indicates an expected call of GetLocationsByIDs.
+func (mr *MockLocationDBStoreMockRecorder) GetLocationsByIDs(ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationsByIDs", reflect.TypeOf((*MockLocationDBStore)(nil).GetLocationsByIDs), ids)
+}
+
+// GetLocationsInBox mocks base method.
+func (m *MockLocationDBStore) GetLocationsInBox(req *model.GetLocationsInBoxRequest) (*model.GetLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationsInBox", req)
+	ret0, _ := ret[0].(*model.GetLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationsInBox indicates an expected call of GetLocationsInBox.
+func (mr *MockLocationDBStoreMockRecorder) GetLocationsInBox(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationsInBox", reflect.TypeOf((*MockLocationDBStore)(nil).GetLocationsInBox), req)
+}
+
+// GetLocationsInPolygon mocks base method.
+func (m *MockLocationDBStore) GetLocationsInPolygon(req *model.GetLocationsInPolygonRequest) (*model.GetLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocationsInPolygon", req)
+	ret0, _ := ret[0].(*model.GetLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocationsInPolygon indicates an expected call of GetLocationsInPolygon.
+func (mr *MockLocationDBStoreMockRecorder) GetLocationsInPolygon(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocationsInPolygon", reflect.TypeOf((*MockLocationDBStore)(nil).GetLocationsInPolygon), req)
+}
+
+// GetNearbyLocations mocks base method.
+func (m *MockLocationDBStore) GetNearbyLocations(req *model.GetNearbyLocationsRequest) (*model.GetNearbyLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNearbyLocations", req)
+	ret0, _ := ret[0].(*model.GetNearbyLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNearbyLocations indicates an expected call of GetNearbyLocations.
+func (mr *MockLocationDBStoreMockRecorder) GetNearbyLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNearbyLocations", reflect.TypeOf((*MockLocationDBStore)(nil).GetNearbyLocations), req)
+}
+
 // GetRoutes mocks base method.
-func (m *MockLocationDBStore) GetRoutes() (*model.GetAllLocationsDBResponse, error) {
+func (m *MockLocationDBStore) GetRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRoutes")
-	ret0, _ := ret[0].(*model.GetAllLocationsDBResponse)
+	ret := m.ctrl.Call(m, "GetRoutes", req)
+	ret0, _ := ret[0].(*model.GetRoutesResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetRoutes indicates an expected call of GetRoutes.
-func (mr *MockLocationDBStoreMockRecorder) GetRoutes() *gomock.Call {
+func (mr *MockLocationDBStoreMockRecorder) GetRoutes(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoutes", reflect.TypeOf((*MockLocationDBStore)(nil).GetRoutes), req)
+}
+
+// PatchLocations mocks base method.
+func (m *MockLocationDBStore) PatchLocations(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchLocations", req)
+	ret0, _ := ret[0].(*model.UpdateLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchLocations indicates an expected call of PatchLocations.
+func (mr *MockLocationDBStoreMockRecorder) PatchLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchLocations", reflect.TypeOf((*MockLocationDBStore)(nil).PatchLocations), req)
+}
+
+// PurgeArchivedLocations mocks base method.
+func (m *MockLocationDBStore) PurgeArchivedLocations(archivedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeArchivedLocations", archivedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeArchivedLocations indicates an expected call of PurgeArchivedLocations.
+func (mr *MockLocationDBStoreMockRecorder) PurgeArchivedLocations(archivedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeArchivedLocations", reflect.TypeOf((*MockLocationDBStore)(nil).PurgeArchivedLocations), archivedBefore)
+}
+
+// RestoreLocations mocks base method.
+func (m *MockLocationDBStore) RestoreLocations(req *model.RestoreLocationsRequest) (*model.RestoreLocationsResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreLocations", req)
+	ret0, _ := ret[0].(*model.RestoreLocationsResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreLocations indicates an expected call of RestoreLocations.
+func (mr *MockLocationDBStoreMockRecorder) RestoreLocations(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreLocations", reflect.TypeOf((*MockLocationDBStore)(nil).RestoreLocations), req)
+}
+
+// RevertLocation mocks base method.
+func (m *MockLocationDBStore) RevertLocation(req *model.RevertLocationRequest) (*model.GetLocationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertLocation", req)
+	ret0, _ := ret[0].(*model.GetLocationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevertLocation indicates an expected call of RevertLocation.
+func (mr *MockLocationDBStoreMockRecorder) RevertLocation(req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertLocation", reflect.TypeOf((*MockLocationDBStore)(nil).RevertLocation), req)
+}
+
+// StreamLocations mocks base method.
+func (m *MockLocationDBStore) StreamLocations(fn func(*model.GetLocationResponse) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamLocations", fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamLocations indicates an expected call of StreamLocations.
+func (mr *MockLocationDBStoreMockRecorder) StreamLocations(fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamLocations", reflect.TypeOf((*MockLocationDBStore)(nil).StreamLocations), fn)
+}
+
+// TextSearchLocations mocks base method.
+func (m *MockLocationDBStore) TextSearchLocations(query string, limit int64) ([]model.LocationSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TextSearchLocations", query, limit)
+	ret0, _ := ret[0].([]model.LocationSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TextSearchLocations indicates an expected call of TextSearchLocations.
+func (mr *MockLocationDBStoreMockRecorder) TextSearchLocations(query, limit any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoutes", reflect.TypeOf((*MockLocationDBStore)(nil).GetRoutes))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TextSearchLocations", reflect.TypeOf((*MockLocationDBStore)(nil).TextSearchLocations), query, limit)
 }
 
 // UpdateLocations mocks base method.
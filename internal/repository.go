@@ -2,10 +2,17 @@ package internal
 
 import (
 	"context"
-	"location-api/configs"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"location-api/internal/cache"
 	"location-api/internal/helper"
 	"location-api/model"
 	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -16,27 +23,89 @@ import (
 
 type Store interface {
 	CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error)
+	CreateLocations(req *model.CreateLocationsRequest) (*model.CreateLocationsResponse, error)
 	GetLocation(req *model.GetLocationRequest) (*model.GetLocationResponse, error)
+	GetLocationAsOf(id string, asOf time.Time) (*model.GetLocationResponse, error)
+	GetLocationHistory(req *model.GetLocationHistoryRequest) (*model.GetLocationHistoryResponse, error)
+	RevertLocation(req *model.RevertLocationRequest) (*model.GetLocationResponse, error)
 	GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error)
+	StreamLocations(fn func(location *model.GetLocationResponse) error) error
 	UpdateLocations(req *model.UpdateLocationsRequest) (*model.UpdateLocationsResponse, error)
-	GetRoutes() (*model.GetAllLocationsDBResponse, error)
+	PatchLocations(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error)
+	DeleteLocation(req *model.DeleteLocationRequest) (*model.DeleteLocationResponse, error)
+	DeleteLocations(req *model.DeleteLocationsRequest) (*model.DeleteLocationsResponse, error)
+	ArchiveLocations(req *model.ArchiveLocationsRequest) (*model.ArchiveLocationsResponse, error)
+	RestoreLocations(req *model.RestoreLocationsRequest) (*model.RestoreLocationsResponse, error)
+	GetArchivedLocations(req *model.GetArchivedLocationsRequest) (*model.GetArchivedLocationsResponse, error)
+	PurgeArchivedLocations(archivedBefore time.Time) (int64, error)
+	GetRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error)
+	GetLocationsByIDs(ids []string) (*model.GetLocationsResponse, error)
+	GetNearbyLocations(req *model.GetNearbyLocationsRequest) (*model.GetNearbyLocationsResponse, error)
+	TextSearchLocations(query string, limit int64) ([]model.LocationSearchResult, error)
+	FuzzySearchLocations(query string, limit int64) ([]model.LocationSearchResult, error)
+	GetLocationsInBox(req *model.GetLocationsInBoxRequest) (*model.GetLocationsResponse, error)
+	GetLocationsInPolygon(req *model.GetLocationsInPolygonRequest) (*model.GetLocationsResponse, error)
 }
 
 type MongoDBStore struct {
-	Client *mongo.Client
+	Client        *mongo.Client
+	Cache         cache.Cache
+	routes        *cache.Loader
+	timeout       time.Duration
+	cacheTTL      time.Duration
+	cacheStaleTTL time.Duration
 }
 
+// StoreOption customises a MongoDBStore created by NewStore.
+type StoreOption func(*MongoDBStore)
+
 const cacheKey = "cached_db_locations"
 const cacheDuration = 30 * time.Second
+
+// cacheStaleDuration is how long cached routes are still served, while they are
+// refreshed in the background, once cacheDuration has passed.
+const cacheStaleDuration = 5 * time.Minute
 const dbTimeout = 5 * time.Minute
 
-func NewStore() *MongoDBStore {
-	config, err := configs.LoadConfig()
-	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+const geoField = "location"
+const archivedField = "deleted_at"
+const versionField = "version"
+const historyCollection = "location_history"
+const anonymousActor = "anonymous"
+const systemActor = "system"
+const defaultPageLimit = 10
+
+// routesCoordinateScale rounds the coordinates of a routes query to 4 decimals,
+// about 11 metres, so nearby requests share a cached result.
+const routesCoordinateScale = 1e4
+const metersPerKilometer = 1000
+const purgeBatchSize = 1000
+const fuzzyMatchesField = "fuzzy_matches"
+
+// WithDBTimeout sets the timeout of database operations. Values below one keep
+// the default.
+func WithDBTimeout(timeout time.Duration) StoreOption {
+	return func(store *MongoDBStore) {
+		if timeout > 0 {
+			store.timeout = timeout
+		}
+	}
+}
+
+// WithRoutesCache sets how long cached routes are fresh and how long after that
+// they are still served while being refreshed. A ttl below one keeps the
+// defaults.
+func WithRoutesCache(ttl, staleTTL time.Duration) StoreOption {
+	return func(store *MongoDBStore) {
+		if ttl > 0 {
+			store.cacheTTL = ttl
+			store.cacheStaleTTL = max(staleTTL, 0)
+		}
 	}
+}
 
-	clientOptions := options.Client().ApplyURI(config.MongoDB.URI)
+func NewStore(uri string, c cache.Cache, opts ...StoreOption) *MongoDBStore {
+	clientOptions := options.Client().ApplyURI(uri)
 
 	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
@@ -47,23 +116,265 @@ func NewStore() *MongoDBStore {
 		log.Fatal("Unable to access MongoDB:", err)
 	}
 
-	return &MongoDBStore{
-		Client: client,
+	store := newStore(client, c, opts...)
+
+	if err = store.ensureIndexes(); err != nil {
+		log.Fatal("Unable to create indexes:", err)
 	}
+
+	return store
 }
 
-func (store *MongoDBStore) CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error) {
+func newStore(client *mongo.Client, c cache.Cache, opts ...StoreOption) *MongoDBStore {
+	store := &MongoDBStore{
+		Client:        client,
+		Cache:         c,
+		timeout:       dbTimeout,
+		cacheTTL:      cacheDuration,
+		cacheStaleTTL: cacheStaleDuration,
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	store.routes = cache.NewLoader(c, "routes", store.cacheTTL, store.cacheStaleTTL)
+
+	return store
+}
+
+// ensureIndexes backfills the GeoJSON point of documents written before the
+// location field existed and creates the 2dsphere index used by $geoNear and
+// $geoWithin, the coordinate index used by bounding box queries, the index
+// used to find archived locations, the indexes behind the filters and sorts of
+// GetLocations, the text index used by name search and the index that orders
+// the history of a location. The text index does not stem words, since names
+// are not written in any one language.
+func (store *MongoDBStore) ensureIndexes() error {
 	collection := store.Client.Database("location").Collection("locations")
 
-	doc := bson.M{
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	filter := bson.M{
+		geoField:    bson.M{"$exists": false},
+		"latitude":  bson.M{"$gte": -90, "$lte": 90},
+		"longitude": bson.M{"$gte": -180, "$lte": 180},
+	}
+
+	backfill := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{geoField: storedGeoPoint()}}},
+	}
+
+	result, err := collection.UpdateMany(ctx, filter, backfill)
+	if err != nil {
+		return err
+	}
+
+	if result.ModifiedCount > 0 {
+		log.Println("INFO: Backfilled geo points:", result.ModifiedCount)
+	}
+
+	result, err = collection.UpdateMany(ctx,
+		bson.M{versionField: bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{versionField: 1}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.ModifiedCount > 0 {
+		log.Println("INFO: Backfilled versions:", result.ModifiedCount)
+	}
+
+	_, err = collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: geoField, Value: "2dsphere"}}},
+		{Keys: bson.D{{Key: "latitude", Value: 1}, {Key: "longitude", Value: 1}}},
+		{Keys: bson.D{{Key: archivedField, Value: 1}}},
+		{Keys: bson.D{{Key: "name", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "name", Value: "text"}}, Options: options.Index().SetDefaultLanguage("none")},
+	})
+	if err != nil {
+		return err
+	}
+
+	history := store.Client.Database("location").Collection(historyCollection)
+	_, err = history.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "location_id", Value: 1}, {Key: "at", Value: -1}},
+	})
+
+	return err
+}
+
+// storedGeoPoint is the aggregation expression that rebuilds the GeoJSON point
+// from the latitude and longitude fields of the document itself.
+func storedGeoPoint() bson.M {
+	return bson.M{
+		"type":        "Point",
+		"coordinates": bson.A{"$longitude", "$latitude"},
+	}
+}
+
+// updateWithGeoPoint turns a $set document into an update pipeline that also
+// refreshes the GeoJSON point, so changing a single coordinate keeps the other,
+// and bumps the version. Values are wrapped in $literal because pipeline stages
+// evaluate expressions.
+func updateWithGeoPoint(updateData bson.M) mongo.Pipeline {
+	literals := bson.M{}
+	for field, value := range updateData {
+		literals[field] = bson.M{"$literal": value}
+	}
+
+	return mongo.Pipeline{
+		{{Key: "$set", Value: literals}},
+		{{Key: "$set", Value: bson.M{
+			geoField:     storedGeoPoint(),
+			versionField: bson.M{"$add": bson.A{"$" + versionField, 1}},
+		}}},
+	}
+}
+
+// versionFilter narrows filter to the expected version when one is given.
+func versionFilter(filter bson.M, expectedVersion *int64) bson.M {
+	if expectedVersion != nil {
+		filter[versionField] = *expectedVersion
+	}
+
+	return filter
+}
+
+// isVersionConflict reports whether an update that matched nothing failed only
+// because the active location has moved past the expected version.
+func isVersionConflict(collection *mongo.Collection, objectID primitive.ObjectID, expectedVersion *int64) bool {
+	if expectedVersion == nil {
+		return false
+	}
+
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+
+	err := collection.FindOne(context.TODO(), activeFilter(bson.M{"_id": objectID})).Decode(&current)
+
+	return err == nil && current.Version != *expectedVersion
+}
+
+// activeFilter hides archived locations from filter.
+func activeFilter(filter bson.M) bson.M {
+	filter[archivedField] = bson.M{"$exists": false}
+	return filter
+}
+
+// locationDoc builds the stored document for a new location.
+func locationDoc(req *model.CreateLocationRequest, createdAt time.Time) bson.M {
+	return bson.M{
 		"name":         req.Name,
-		"latitude":     req.Latitude,
-		"longitude":    req.Longitude,
+		"latitude":     *req.Latitude,
+		"longitude":    *req.Longitude,
 		"marker_color": req.MarkerColor,
-		"created_at":   time.Now(),
+		geoField:       geoPoint(*req.Latitude, *req.Longitude),
+		versionField:   1,
+		"created_at":   createdAt,
 	}
+}
+
+// createdState is the state a new location starts its history with.
+func createdState(req *model.CreateLocationRequest) *model.LocationState {
+	return &model.LocationState{
+		Name:        req.Name,
+		Latitude:    *req.Latitude,
+		Longitude:   *req.Longitude,
+		MarkerColor: req.MarkerColor,
+	}
+}
+
+// geoPoint builds the GeoJSON point stored next to latitude and longitude.
+// GeoJSON orders coordinates as longitude, latitude.
+func geoPoint(latitude, longitude float64) bson.M {
+	return bson.M{
+		"type":        "Point",
+		"coordinates": bson.A{longitude, latitude},
+	}
+}
+
+// locationState picks the fields the history keeps from a stored location.
+func locationState(location *model.GetLocationResponse) *model.LocationState {
+	return &model.LocationState{
+		Name:        location.Name,
+		Latitude:    location.Latitude,
+		Longitude:   location.Longitude,
+		MarkerColor: location.MarkerColor,
+	}
+}
+
+// changedState returns before with the fields of an update applied, which is the
+// state the update left the location in.
+func changedState(before *model.LocationState, set map[string]interface{}, unset []string) *model.LocationState {
+	after := *before
+
+	for field, value := range set {
+		switch field {
+		case "name":
+			after.Name, _ = value.(string)
+		case "latitude":
+			after.Latitude, _ = value.(float64)
+		case "longitude":
+			after.Longitude, _ = value.(float64)
+		case "marker_color":
+			after.MarkerColor, _ = value.(string)
+		}
+	}
+
+	for _, field := range unset {
+		if field == "marker_color" {
+			after.MarkerColor = ""
+		}
+	}
+
+	return &after
+}
+
+func historyEntry(action, actor, id string, version int64, before, after *model.LocationState) model.LocationHistoryEntry {
+	if actor == "" {
+		actor = anonymousActor
+	}
+
+	return model.LocationHistoryEntry{
+		LocationID: id,
+		Action:     action,
+		Version:    version,
+		Actor:      actor,
+		At:         time.Now().UTC(),
+		Before:     before,
+		After:      after,
+	}
+}
+
+// recordHistory appends entries to the history collection. The change itself has
+// already been written by then, so a failure is logged rather than returned.
+func (store *MongoDBStore) recordHistory(entries ...model.LocationHistoryEntry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	collection := store.Client.Database("location").Collection(historyCollection)
+
+	docs := make([]interface{}, len(entries))
+	for i := range entries {
+		docs[i] = entries[i]
+	}
+
+	if _, err := collection.InsertMany(context.TODO(), docs); err != nil {
+		log.Println("ERROR: location history cannot be recorded:", err)
+	}
+}
 
-	result, err := collection.InsertOne(context.TODO(), doc)
+func (store *MongoDBStore) CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	result, err := collection.InsertOne(context.TODO(), locationDoc(req, time.Now()))
 	if err != nil {
 		return nil, err
 	}
@@ -73,9 +384,63 @@ func (store *MongoDBStore) CreateLocation(req *model.CreateLocationRequest) (*mo
 		return nil, mongo.ErrNilDocument
 	}
 
+	store.recordHistory(historyEntry(model.HistoryActionCreate, req.Actor, insertedID.Hex(), 1, nil, createdState(req)))
+
 	return &model.CreateLocationResponse{ID: insertedID.Hex()}, nil
 }
 
+// CreateLocations inserts every location in a single unordered InsertMany so that
+// one failed write does not stop the rest. Failed indexes refer to req.Locations.
+func (store *MongoDBStore) CreateLocations(req *model.CreateLocationsRequest) (*model.CreateLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	now := time.Now()
+	ids := make([]primitive.ObjectID, len(req.Locations))
+	docs := make([]interface{}, len(req.Locations))
+
+	for i := range req.Locations {
+		ids[i] = primitive.NewObjectID()
+
+		doc := locationDoc(&req.Locations[i], now)
+		doc["_id"] = ids[i]
+		docs[i] = doc
+	}
+
+	failed := []model.CreateLocationError{}
+	failedIndexes := make(map[int]bool)
+
+	_, err := collection.InsertMany(context.TODO(), docs, options.InsertMany().SetOrdered(false))
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+			return nil, err
+		}
+
+		for _, writeErr := range bulkErr.WriteErrors {
+			failedIndexes[writeErr.Index] = true
+			failed = append(failed, model.CreateLocationError{Index: writeErr.Index, Error: writeErr.Message})
+		}
+	}
+
+	createdIDs := make([]string, 0, len(ids)-len(failedIndexes))
+	history := make([]model.LocationHistoryEntry, 0, len(ids)-len(failedIndexes))
+
+	for i, id := range ids {
+		if !failedIndexes[i] {
+			createdIDs = append(createdIDs, id.Hex())
+			history = append(history, historyEntry(model.HistoryActionCreate, req.Actor, id.Hex(), 1, nil, createdState(&req.Locations[i])))
+		}
+	}
+
+	store.recordHistory(history...)
+
+	return &model.CreateLocationsResponse{
+		CreatedIDs:   createdIDs,
+		Failed:       failed,
+		CreatedCount: int64(len(createdIDs)),
+	}, nil
+}
+
 func (store *MongoDBStore) GetLocation(req *model.GetLocationRequest) (*model.GetLocationResponse, error) {
 	collection := store.Client.Database("location").Collection("locations")
 
@@ -84,7 +449,7 @@ func (store *MongoDBStore) GetLocation(req *model.GetLocationRequest) (*model.Ge
 		return nil, err
 	}
 
-	filter := bson.M{"_id": objectID}
+	filter := activeFilter(bson.M{"_id": objectID})
 
 	var location model.GetLocationResponse
 	if err := collection.FindOne(context.TODO(), filter).Decode(&location); err != nil {
@@ -101,175 +466,1175 @@ func (store *MongoDBStore) GetLocation(req *model.GetLocationRequest) (*model.Ge
 		Latitude:    location.Latitude,
 		Longitude:   location.Longitude,
 		MarkerColor: location.MarkerColor,
+		Version:     location.Version,
+		CreatedAt:   location.CreatedAt,
+		UpdatedAt:   location.UpdatedAt,
 	}, nil
 }
 
-func (store *MongoDBStore) GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error) {
-	collection := store.Client.Database("location").Collection("locations")
-
-	var page, limit = int64(req.Page), int64(req.Limit)
-	if page < 1 {
-		page = 1
+// GetLocationAsOf rebuilds a location from the last history entry recorded at or
+// before asOf. A location that did not exist yet, or had been archived or
+// deleted by then, is reported as mongo.ErrNoDocuments.
+func (store *MongoDBStore) GetLocationAsOf(id string, asOf time.Time) (*model.GetLocationResponse, error) {
+	entry, err := store.lastHistoryEntry(bson.M{"location_id": id, "at": bson.M{"$lte": asOf}})
+	if err != nil {
+		return nil, err
 	}
 
-	if limit < 1 {
-		limit = 10
+	if entry.After == nil || entry.Action == model.HistoryActionArchive {
+		return nil, mongo.ErrNoDocuments
 	}
 
-	skip := (page - 1) * limit
+	return &model.GetLocationResponse{
+		ID:          id,
+		Name:        entry.After.Name,
+		Latitude:    entry.After.Latitude,
+		Longitude:   entry.After.Longitude,
+		MarkerColor: entry.After.MarkerColor,
+		Version:     entry.Version,
+	}, nil
+}
+
+// lastHistoryEntry returns the most recent history entry matching filter.
+func (store *MongoDBStore) lastHistoryEntry(filter bson.M) (*model.LocationHistoryEntry, error) {
+	collection := store.Client.Database("location").Collection(historyCollection)
 
-	opts := options.Find().SetSkip(skip).SetLimit(limit)
-	filter := bson.M{}
+	opts := options.FindOne().SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}})
 
-	cursor, err := collection.Find(context.TODO(), filter, opts)
-	if err != nil {
+	var entry model.LocationHistoryEntry
+	if err := collection.FindOne(context.TODO(), filter, opts).Decode(&entry); err != nil {
 		return nil, err
 	}
-	defer cursor.Close(context.TODO())
 
-	var locations []model.GetLocationResponse
+	return &entry, nil
+}
 
-	for cursor.Next(context.TODO()) {
-		var location model.GetLocationResponse
-		if err := cursor.Decode(&location); err != nil {
-			return nil, err
-		}
+// RevertLocation sets an active location back to the fields it had at an
+// earlier version or time. The revert is a change like any other: it bumps the
+// version and is recorded in the history, so it can be reverted in turn.
+func (store *MongoDBStore) RevertLocation(req *model.RevertLocationRequest) (*model.GetLocationResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
 
-		locations = append(locations, location)
+	objectID, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := cursor.Err(); err != nil {
+	target, err := store.revertTarget(req)
+	if err != nil {
 		return nil, err
 	}
 
-	if len(locations) == 0 {
-		return nil, mongo.ErrNoDocuments
+	set := bson.M{
+		"name":         target.Name,
+		"latitude":     target.Latitude,
+		"longitude":    target.Longitude,
+		"marker_color": target.MarkerColor,
+		"updated_at":   time.Now(),
 	}
 
-	return &model.GetLocationsResponse{Locations: locations}, nil
-}
-
-func (store *MongoDBStore) UpdateLocations(req *model.UpdateLocationsRequest) (*model.UpdateLocationsResponse, error) {
-	collection := store.Client.Database("location").Collection("locations")
-
-	var updatedIDs []string
+	var before model.GetLocationResponse
+	if err := collection.FindOneAndUpdate(context.TODO(), activeFilter(bson.M{"_id": objectID}), updateWithGeoPoint(set)).Decode(&before); err != nil {
+		return nil, err
+	}
 
-	var failedIDs []string
+	version := before.Version + 1
+	store.recordHistory(historyEntry(model.HistoryActionRevert, req.Actor, req.ID, version, locationState(&before), target))
 
-	var totalModified int64
+	return &model.GetLocationResponse{
+		ID:          req.ID,
+		Name:        target.Name,
+		Latitude:    target.Latitude,
+		Longitude:   target.Longitude,
+		MarkerColor: target.MarkerColor,
+		Version:     version,
+	}, nil
+}
 
-	for _, location := range req.Locations {
-		objectID, err := primitive.ObjectIDFromHex(location.ID)
+// revertTarget looks up the snapshot a revert goes back to. A version, or a time
+// at which the location did not exist, that the history has no state for is
+// reported as mongo.ErrNoDocuments.
+func (store *MongoDBStore) revertTarget(req *model.RevertLocationRequest) (*model.LocationState, error) {
+	if req.Version != nil {
+		entry, err := store.lastHistoryEntry(bson.M{
+			"location_id": req.ID,
+			"version":     *req.Version,
+			"after":       bson.M{"$ne": nil},
+		})
 		if err != nil {
-			failedIDs = append(failedIDs, location.ID)
-			continue
+			return nil, err
 		}
 
-		updateData := bson.M{}
-		orConditions := []bson.M{}
+		return entry.After, nil
+	}
 
-		if location.Name != "" {
-			updateData["name"] = location.Name
-			orConditions = append(orConditions, bson.M{"name": bson.M{"$ne": location.Name}})
-		}
+	asOf, err := time.Parse(time.RFC3339, req.AsOf)
+	if err != nil {
+		return nil, err
+	}
 
-		if location.Latitude != 0.0 {
-			updateData["latitude"] = location.Latitude
-			orConditions = append(orConditions, bson.M{"latitude": bson.M{"$ne": location.Latitude}})
-		}
+	location, err := store.GetLocationAsOf(req.ID, asOf)
+	if err != nil {
+		return nil, err
+	}
 
-		if location.Longitude != 0.0 {
-			updateData["longitude"] = location.Longitude
-			orConditions = append(orConditions, bson.M{"longitude": bson.M{"$ne": location.Longitude}})
-		}
+	return locationState(location), nil
+}
 
-		if location.MarkerColor != "" {
-			updateData["marker_color"] = location.MarkerColor
-			orConditions = append(orConditions, bson.M{"marker_color": bson.M{"$ne": location.MarkerColor}})
-		}
+// GetLocationHistory returns one page of the changes made to a location, most
+// recent first.
+func (store *MongoDBStore) GetLocationHistory(req *model.GetLocationHistoryRequest) (*model.GetLocationHistoryResponse, error) {
+	collection := store.Client.Database("location").Collection(historyCollection)
 
-		if len(updateData) == 0 {
-			failedIDs = append(failedIDs, location.ID)
-			continue
-		}
+	skip, limit := paginate(req.Page, req.Limit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
 
-		filter := bson.M{
-			"_id": objectID,
-			"$or": orConditions,
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
 
-		updateData["updated_at"] = time.Now()
-		update := bson.M{"$set": updateData}
+	cursor, err := collection.Find(ctx, bson.M{"location_id": req.ID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
-		result, err := collection.UpdateOne(context.TODO(), filter, update)
-		if err != nil {
-			failedIDs = append(failedIDs, location.ID)
-			continue
-		}
+	history := []model.LocationHistoryEntry{}
+	if err := cursor.All(ctx, &history); err != nil {
+		return nil, err
+	}
 
-		if result.ModifiedCount > 0 {
-			updatedIDs = append(updatedIDs, location.ID)
-			totalModified += result.ModifiedCount
-		} else {
-			failedIDs = append(failedIDs, location.ID)
-		}
+	return &model.GetLocationHistoryResponse{History: history}, nil
+}
+
+// GetLocations returns one page of the active locations that match the filters,
+// in the requested order with _id breaking ties. Pages after the first are found
+// by the position carried in the cursor rather than by skipping, so they stay
+// fast on large collections and locations created in between neither repeat
+// nor go missing. One extra location is read to tell whether there is a next
+// page.
+func (store *MongoDBStore) GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	filter, err := locationsFilter(req)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(updatedIDs) == 0 && len(failedIDs) == 0 {
-		return nil, mongo.ErrNoDocuments
+	sortField, descending := locationsSort(req)
+
+	direction := 1
+	if descending {
+		direction = -1
 	}
 
-	return &model.UpdateLocationsResponse{
-		UpdatedIDs:   updatedIDs,
-		FailedIDs:    failedIDs,
-		UpdatedCount: totalModified,
-	}, nil
-}
+	skip, limit := paginate(req.Page, req.Limit)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: sortField, Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(limit + 1)
 
-func (store *MongoDBStore) GetRoutes() (*model.GetAllLocationsDBResponse, error) {
-	var cachedLocations model.GetAllLocationsDBResponse
-	if err := helper.GetCache(cacheKey, &cachedLocations); err == nil {
-		log.Println("INFO: Data get from cache.")
-		return &cachedLocations, nil
+	if fields := req.SelectedFields(); len(fields) > 0 {
+		opts.SetProjection(locationsProjection(fields, sortField))
 	}
 
-	log.Println("WARNING: Redis empty, data will get from db...")
+	pageFilter := filter
 
-	collection := store.Client.Database("location").Collection("locations")
+	if req.Cursor != "" {
+		position, err := decodeCursor(req.Cursor, req.Sort)
+		if err != nil {
+			return nil, err
+		}
 
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+		pageFilter = bson.M{"$and": bson.A{filter, afterCursor(sortField, descending, position)}}
+	} else {
+		opts.SetSkip(skip)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
 	defer cancel()
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	cursor, err := collection.Find(ctx, pageFilter, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	var locations []model.GetLocationResponse
+	locations := []model.GetLocationResponse{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	res := &model.GetLocationsResponse{Locations: locations}
 
-	for cursor.Next(ctx) {
-		var location model.GetLocationResponse
-		if err := cursor.Decode(&location); err != nil {
+	if int64(len(locations)) > limit {
+		res.Locations = locations[:limit]
+
+		res.NextCursor, err = encodeCursor(req.Sort, &res.Locations[limit-1], sortField)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Total {
+		total, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
 			return nil, err
 		}
 
-		locations = append(locations, location)
+		res.Total = &total
 	}
 
-	if err := cursor.Err(); err != nil {
-		return nil, err
+	return res, nil
+}
+
+// locationsFilter translates the filters of a request into a query. Values are
+// only ever used as literals: names are escaped before they go into a regular
+// expression and every other value has been validated by the request.
+func locationsFilter(req *model.GetLocationsRequest) (bson.M, error) {
+	filter := activeFilter(bson.M{})
+	names := bson.A{}
+
+	if req.NamePrefix != "" {
+		names = append(names, bson.M{"name": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(req.NamePrefix), Options: "i"}})
 	}
 
-	if len(locations) == 0 {
-		log.Println("ERROR: No documents found in database.")
-		return nil, mongo.ErrNoDocuments
+	if req.NameContains != "" {
+		names = append(names, bson.M{"name": primitive.Regex{Pattern: regexp.QuoteMeta(req.NameContains), Options: "i"}})
+	}
+
+	if len(names) > 0 {
+		filter["$and"] = names
 	}
 
-	dbResponse := &model.GetAllLocationsDBResponse{Locations: locations}
-	_ = helper.SetCache(cacheKey, dbResponse, cacheDuration)
+	if req.MarkerColor != "" {
+		filter["marker_color"] = req.MarkerColor
+	}
 
-	log.Println("INFO: Data write redis.")
+	for field, bounds := range map[string][2]string{
+		"created_at": {req.CreatedAfter, req.CreatedBefore},
+		"updated_at": {req.UpdatedAfter, req.UpdatedBefore},
+	} {
+		condition, err := timeRange(bounds[0], bounds[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(condition) > 0 {
+			filter[field] = condition
+		}
+	}
+
+	return filter, nil
+}
+
+// timeRange builds the condition for times from after to before, both
+// inclusive and both optional.
+func timeRange(after, before string) (bson.M, error) {
+	condition := bson.M{}
+
+	for operator, value := range map[string]string{"$gte": after, "$lte": before} {
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+
+		condition[operator] = t
+	}
+
+	return condition, nil
+}
+
+// locationsSort returns the field to sort by, _id unless another was asked for.
+func locationsSort(req *model.GetLocationsRequest) (field string, descending bool) {
+	field, descending = req.SortField()
+	if field == "" {
+		return "_id", descending
+	}
+
+	return field, descending
+}
+
+// locationsProjection keeps the selected fields along with _id and the sort
+// field, which the cursor is built from.
+func locationsProjection(fields []string, sortField string) bson.M {
+	projection := bson.M{"_id": 1, sortField: 1}
+
+	for _, field := range fields {
+		if field != "id" {
+			projection[field] = 1
+		}
+	}
+
+	return projection
+}
+
+// cursorPosition is what a cursor carries: the sort it was made for and the
+// sort value and _id of the last location on its page.
+type cursorPosition struct {
+	Sort  string             `bson:"s"`
+	Value interface{}        `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// encodeCursor turns the last location on a page into the opaque token that
+// continues after it.
+func encodeCursor(sort string, last *model.GetLocationResponse, sortField string) (string, error) {
+	id, err := primitive.ObjectIDFromHex(last.ID)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := bson.Marshal(cursorPosition{Sort: sort, Value: sortValue(last, sortField), ID: id})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor reads a cursor back, rejecting one that was made for another sort.
+// Cursors come from clients, so the document is validated before it is decoded,
+// and a decoder panic on a crafted one that still gets through is an invalid
+// cursor too.
+func decodeCursor(cursor, sort string) (position *cursorPosition, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, model.ErrInvalidCursor
+	}
+
+	if err := bson.Raw(data).Validate(); err != nil {
+		return nil, model.ErrInvalidCursor
+	}
+
+	defer func() {
+		if recover() != nil {
+			position, err = nil, model.ErrInvalidCursor
+		}
+	}()
+
+	position = &cursorPosition{}
+	if err := bson.Unmarshal(data, position); err != nil || position.Sort != sort {
+		return nil, model.ErrInvalidCursor
+	}
+
+	return position, nil
+}
+
+func sortValue(location *model.GetLocationResponse, field string) interface{} {
+	switch field {
+	case "name":
+		return location.Name
+	case "marker_color":
+		return location.MarkerColor
+	case "latitude":
+		return location.Latitude
+	case "longitude":
+		return location.Longitude
+	case "created_at":
+		if location.CreatedAt != nil {
+			return *location.CreatedAt
+		}
+	case "updated_at":
+		if location.UpdatedAt != nil {
+			return *location.UpdatedAt
+		}
+	}
+
+	return nil
+}
+
+// afterCursor matches the locations that come after the cursor position in the
+// sort order. Locations without the sort field sort before every value, and
+// range operators never match them, so they are handled on their own.
+func afterCursor(field string, descending bool, position *cursorPosition) bson.M {
+	next, idNext := "$gt", bson.M{"$gt": position.ID}
+	if descending {
+		next, idNext = "$lt", bson.M{"$lt": position.ID}
+	}
+
+	if field == "_id" {
+		return bson.M{"_id": idNext}
+	}
+
+	if position.Value == nil {
+		if descending {
+			return bson.M{field: nil, "_id": idNext}
+		}
+
+		return bson.M{"$or": bson.A{
+			bson.M{field: nil, "_id": idNext},
+			bson.M{field: bson.M{"$ne": nil}},
+		}}
+	}
+
+	after := bson.A{
+		bson.M{field: bson.M{next: position.Value}},
+		bson.M{field: position.Value, "_id": idNext},
+	}
+
+	if descending {
+		after = append(after, bson.M{field: nil})
+	}
+
+	return bson.M{"$or": after}
+}
+
+// StreamLocations calls fn for every active location in _id order without
+// loading the whole collection into memory, stopping at the first error.
+func (store *MongoDBStore) StreamLocations(fn func(location *model.GetLocationResponse) error) error {
+	collection := store.Client.Database("location").Collection("locations")
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := collection.Find(context.TODO(), activeFilter(bson.M{}), opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var location model.GetLocationResponse
+		if err := cursor.Decode(&location); err != nil {
+			return err
+		}
+
+		if err := fn(&location); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+func (store *MongoDBStore) UpdateLocations(req *model.UpdateLocationsRequest) (*model.UpdateLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	updatedIDs := []string{}
+	failedIDs := []string{}
+	conflictIDs := []string{}
+	history := []model.LocationHistoryEntry{}
+
+	var totalModified int64
+
+	for _, location := range req.Locations {
+		objectID, err := primitive.ObjectIDFromHex(location.ID)
+		if err != nil {
+			failedIDs = append(failedIDs, location.ID)
+			continue
+		}
+
+		updateData := bson.M{}
+		orConditions := []bson.M{}
+
+		if location.Name != "" {
+			updateData["name"] = location.Name
+			orConditions = append(orConditions, bson.M{"name": bson.M{"$ne": location.Name}})
+		}
+
+		if location.Latitude != nil {
+			updateData["latitude"] = *location.Latitude
+			orConditions = append(orConditions, bson.M{"latitude": bson.M{"$ne": *location.Latitude}})
+		}
+
+		if location.Longitude != nil {
+			updateData["longitude"] = *location.Longitude
+			orConditions = append(orConditions, bson.M{"longitude": bson.M{"$ne": *location.Longitude}})
+		}
+
+		if location.MarkerColor != "" {
+			updateData["marker_color"] = location.MarkerColor
+			orConditions = append(orConditions, bson.M{"marker_color": bson.M{"$ne": location.MarkerColor}})
+		}
+
+		if len(updateData) == 0 {
+			failedIDs = append(failedIDs, location.ID)
+			continue
+		}
+
+		filter := versionFilter(activeFilter(bson.M{
+			"_id": objectID,
+			"$or": orConditions,
+		}), location.ExpectedVersion)
+
+		updateData["updated_at"] = time.Now()
+
+		var update interface{} = bson.M{"$set": updateData, "$inc": bson.M{versionField: 1}}
+
+		_, latitudeChanged := updateData["latitude"]
+		_, longitudeChanged := updateData["longitude"]
+
+		if latitudeChanged || longitudeChanged {
+			update = updateWithGeoPoint(updateData)
+		}
+
+		var before model.GetLocationResponse
+
+		err = collection.FindOneAndUpdate(context.TODO(), filter, update).Decode(&before)
+
+		switch {
+		case err == nil:
+			updatedIDs = append(updatedIDs, location.ID)
+			totalModified++
+
+			history = append(history, historyEntry(model.HistoryActionUpdate, req.Actor, location.ID, before.Version+1,
+				locationState(&before), changedState(locationState(&before), updateData, nil)))
+		case errors.Is(err, mongo.ErrNoDocuments) && isVersionConflict(collection, objectID, location.ExpectedVersion):
+			conflictIDs = append(conflictIDs, location.ID)
+		default:
+			failedIDs = append(failedIDs, location.ID)
+		}
+	}
+
+	store.recordHistory(history...)
+
+	if len(updatedIDs) == 0 && len(failedIDs) == 0 && len(conflictIDs) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return &model.UpdateLocationsResponse{
+		UpdatedIDs:   updatedIDs,
+		FailedIDs:    failedIDs,
+		ConflictIDs:  conflictIDs,
+		UpdatedCount: totalModified,
+	}, nil
+}
+
+// PatchLocations applies a merge patch to every location. Unlike
+// UpdateLocations, a patch that leaves a location as it was still counts as
+// applied, since applying the same patch twice must give the same result. It
+// still bumps the version, so a repeated patch with an expected version
+// conflicts.
+func (store *MongoDBStore) PatchLocations(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	updatedIDs := []string{}
+	failedIDs := []string{}
+	conflictIDs := []string{}
+	history := []model.LocationHistoryEntry{}
+
+	var totalModified int64
+
+	for i := range req.Locations {
+		patch := &req.Locations[i]
+
+		objectID, err := primitive.ObjectIDFromHex(patch.ID)
+		if err != nil {
+			failedIDs = append(failedIDs, patch.ID)
+			continue
+		}
+
+		set, unset, err := patch.Changes()
+		if err != nil {
+			failedIDs = append(failedIDs, patch.ID)
+			continue
+		}
+
+		set["updated_at"] = time.Now()
+
+		update := updateWithGeoPoint(set)
+		if len(unset) > 0 {
+			update = append(update, bson.D{{Key: "$unset", Value: unset}})
+		}
+
+		filter := versionFilter(activeFilter(bson.M{"_id": objectID}), patch.ExpectedVersion)
+
+		var before model.GetLocationResponse
+
+		err = collection.FindOneAndUpdate(context.TODO(), filter, update).Decode(&before)
+
+		switch {
+		case err == nil:
+			updatedIDs = append(updatedIDs, patch.ID)
+			totalModified++
+
+			history = append(history, historyEntry(model.HistoryActionUpdate, req.Actor, patch.ID, before.Version+1,
+				locationState(&before), changedState(locationState(&before), set, unset)))
+		case errors.Is(err, mongo.ErrNoDocuments) && isVersionConflict(collection, objectID, patch.ExpectedVersion):
+			conflictIDs = append(conflictIDs, patch.ID)
+		default:
+			failedIDs = append(failedIDs, patch.ID)
+		}
+	}
+
+	store.recordHistory(history...)
+
+	return &model.UpdateLocationsResponse{
+		UpdatedIDs:   updatedIDs,
+		FailedIDs:    failedIDs,
+		ConflictIDs:  conflictIDs,
+		UpdatedCount: totalModified,
+	}, nil
+}
+
+func (store *MongoDBStore) DeleteLocation(req *model.DeleteLocationRequest) (*model.DeleteLocationResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	objectID, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var before model.GetLocationResponse
+	if err := collection.FindOneAndDelete(context.TODO(), bson.M{"_id": objectID}).Decode(&before); err != nil {
+		return nil, err
+	}
+
+	store.recordHistory(historyEntry(model.HistoryActionDelete, req.Actor, req.ID, before.Version, locationState(&before), nil))
+
+	return &model.DeleteLocationResponse{ID: req.ID}, nil
+}
+
+func (store *MongoDBStore) DeleteLocations(req *model.DeleteLocationsRequest) (*model.DeleteLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	deletedIDs := []string{}
+	failedIDs := []string{}
+	history := []model.LocationHistoryEntry{}
+
+	var totalDeleted int64
+
+	for _, id := range req.IDs {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			failedIDs = append(failedIDs, id)
+			continue
+		}
+
+		var before model.GetLocationResponse
+		if err := collection.FindOneAndDelete(context.TODO(), bson.M{"_id": objectID}).Decode(&before); err != nil {
+			failedIDs = append(failedIDs, id)
+			continue
+		}
+
+		deletedIDs = append(deletedIDs, id)
+		totalDeleted++
+
+		history = append(history, historyEntry(model.HistoryActionDelete, req.Actor, id, before.Version, locationState(&before), nil))
+	}
+
+	store.recordHistory(history...)
+
+	return &model.DeleteLocationsResponse{
+		DeletedIDs:   deletedIDs,
+		FailedIDs:    failedIDs,
+		DeletedCount: totalDeleted,
+	}, nil
+}
+
+// ArchiveLocations marks active locations as deleted without removing them, so
+// they disappear from every read but can still be restored.
+func (store *MongoDBStore) ArchiveLocations(req *model.ArchiveLocationsRequest) (*model.ArchiveLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	archivedIDs := []string{}
+	failedIDs := []string{}
+	history := []model.LocationHistoryEntry{}
+
+	var totalArchived int64
+
+	for _, id := range req.IDs {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			failedIDs = append(failedIDs, id)
+			continue
+		}
+
+		filter := activeFilter(bson.M{"_id": objectID})
+		update := bson.M{
+			"$set": bson.M{archivedField: time.Now()},
+			"$inc": bson.M{versionField: 1},
+		}
+
+		var before model.GetLocationResponse
+		if err := collection.FindOneAndUpdate(context.TODO(), filter, update).Decode(&before); err != nil {
+			failedIDs = append(failedIDs, id)
+			continue
+		}
+
+		archivedIDs = append(archivedIDs, id)
+		totalArchived++
+
+		state := locationState(&before)
+		history = append(history, historyEntry(model.HistoryActionArchive, req.Actor, id, before.Version+1, state, state))
+	}
+
+	store.recordHistory(history...)
+
+	return &model.ArchiveLocationsResponse{
+		ArchivedIDs:   archivedIDs,
+		FailedIDs:     failedIDs,
+		ArchivedCount: totalArchived,
+	}, nil
+}
+
+func (store *MongoDBStore) RestoreLocations(req *model.RestoreLocationsRequest) (*model.RestoreLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	restoredIDs := []string{}
+	failedIDs := []string{}
+	history := []model.LocationHistoryEntry{}
+
+	var totalRestored int64
+
+	for _, id := range req.IDs {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			failedIDs = append(failedIDs, id)
+			continue
+		}
+
+		filter := bson.M{"_id": objectID, archivedField: bson.M{"$exists": true}}
+		update := bson.M{
+			"$unset": bson.M{archivedField: ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$inc":   bson.M{versionField: 1},
+		}
+
+		var before model.GetLocationResponse
+		if err := collection.FindOneAndUpdate(context.TODO(), filter, update).Decode(&before); err != nil {
+			failedIDs = append(failedIDs, id)
+			continue
+		}
+
+		restoredIDs = append(restoredIDs, id)
+		totalRestored++
+
+		state := locationState(&before)
+		history = append(history, historyEntry(model.HistoryActionRestore, req.Actor, id, before.Version+1, state, state))
+	}
+
+	store.recordHistory(history...)
+
+	return &model.RestoreLocationsResponse{
+		RestoredIDs:   restoredIDs,
+		FailedIDs:     failedIDs,
+		RestoredCount: totalRestored,
+	}, nil
+}
+
+func (store *MongoDBStore) GetArchivedLocations(req *model.GetArchivedLocationsRequest) (*model.GetArchivedLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	skip, limit := paginate(req.Page, req.Limit)
+	opts := options.Find().
+		SetSort(bson.D{{Key: archivedField, Value: -1}, {Key: "_id", Value: 1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{archivedField: bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locations := []model.ArchivedLocation{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	return &model.GetArchivedLocationsResponse{Locations: locations}, nil
+}
+
+// PurgeArchivedLocations permanently removes locations archived before
+// archivedBefore and returns how many were removed. Locations are read and
+// deleted purgeBatchSize at a time, and each batch is recorded in the history
+// with a single insert.
+func (store *MongoDBStore) PurgeArchivedLocations(archivedBefore time.Time) (int64, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	filter := bson.M{archivedField: bson.M{"$lt": archivedBefore}}
+
+	var purged int64
+
+	for {
+		cursor, err := collection.Find(ctx, filter, options.Find().SetLimit(purgeBatchSize))
+		if err != nil {
+			return purged, err
+		}
+
+		batch := []model.GetLocationResponse{}
+		if err := cursor.All(ctx, &batch); err != nil {
+			return purged, err
+		}
+
+		if len(batch) == 0 {
+			return purged, nil
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(batch))
+		history := make([]model.LocationHistoryEntry, 0, len(batch))
+
+		for i := range batch {
+			id, err := primitive.ObjectIDFromHex(batch[i].ID)
+			if err != nil {
+				return purged, err
+			}
+
+			ids = append(ids, id)
+			history = append(history, historyEntry(model.HistoryActionPurge, systemActor, batch[i].ID, batch[i].Version, locationState(&batch[i]), nil))
+		}
+
+		// The archive filter is kept so that a location restored since it was
+		// read is not deleted.
+		result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, archivedField: filter[archivedField]})
+		if err != nil {
+			return purged, err
+		}
+
+		store.recordHistory(history...)
+		purged += result.DeletedCount
+
+		if len(batch) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// GetRoutes returns the locations nearest to a coordinate rounded to 4
+// decimals, so that the distances of a cached result are the same for every
+// request that shares it.
+func (store *MongoDBStore) GetRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error) {
+	var routes model.GetRoutesResponse
+
+	rounded := roundRoutesRequest(req)
+
+	err := store.routes.Load(routesCacheKey(rounded), &routes, func() (interface{}, error) {
+		log.Println("WARNING: Cache empty or stale, data will get from db...")
+		return store.queryRoutes(rounded)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &routes, nil
+}
+
+func (store *MongoDBStore) queryRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		geoNearStage(*req.Latitude, *req.Longitude, req.MinDistance, req.MaxDistance),
+	}
+
+	if req.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: req.Limit}})
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	routes := []model.Route{}
+	if err := cursor.All(ctx, &routes); err != nil {
+		return nil, err
+	}
+
+	return &model.GetRoutesResponse{Routes: routes}, nil
+}
+
+// routesCacheKey scopes cached routes to the query parameters. Every key shares
+// the cacheKey prefix so a single prefix delete invalidates all of them.
+func routesCacheKey(req *model.GetRoutesRequest) string {
+	return fmt.Sprintf("%s:%.4f:%.4f:%d:%g:%g", cacheKey, *req.Latitude, *req.Longitude, req.Limit, req.MaxDistance, req.MinDistance)
+}
+
+// roundRoutesRequest returns a copy of req with its coordinate rounded to
+// routesCoordinateScale.
+func roundRoutesRequest(req *model.GetRoutesRequest) *model.GetRoutesRequest {
+	latitude := math.Round(*req.Latitude*routesCoordinateScale) / routesCoordinateScale
+	longitude := math.Round(*req.Longitude*routesCoordinateScale) / routesCoordinateScale
+
+	rounded := *req
+	rounded.Latitude = &latitude
+	rounded.Longitude = &longitude
+
+	return &rounded
+}
+
+// GetLocationsByIDs returns the locations whose IDs are in ids. IDs that are not
+// valid ObjectIDs or do not exist are left out of the response.
+func (store *MongoDBStore) GetLocationsByIDs(ids []string) (*model.GetLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			continue
+		}
+
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	locations := []model.GetLocationResponse{}
+	if len(objectIDs) == 0 {
+		return &model.GetLocationsResponse{Locations: locations}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, activeFilter(bson.M{"_id": bson.M{"$in": objectIDs}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	return &model.GetLocationsResponse{Locations: locations}, nil
+}
+
+func (store *MongoDBStore) GetNearbyLocations(req *model.GetNearbyLocationsRequest) (*model.GetNearbyLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	skip, limit := paginate(req.Page, req.Limit)
+
+	pipeline := mongo.Pipeline{
+		geoNearStage(*req.Latitude, *req.Longitude, 0, req.RadiusKm),
+		{{Key: "$skip", Value: skip}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locations := []model.NearbyLocation{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	return &model.GetNearbyLocationsResponse{Locations: locations}, nil
+}
+
+// TextSearchLocations returns the active locations whose names match query in
+// the text index, best match first, with the text score as their score.
+func (store *MongoDBStore) TextSearchLocations(query string, limit int64) ([]model.LocationSearchResult, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"name": 1, "latitude": 1, "longitude": 1, "marker_color": 1, "score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, activeFilter(bson.M{"$text": bson.M{"$search": query}}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locations := []model.LocationSearchResult{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// FuzzySearchLocations returns up to limit active locations ranked by how many
+// of the query's trigrams their names contain, so that misspelt and half typed
+// names can still be found. Names with too few of them to ever be relevant
+// enough are left out. Every result has a score of zero.
+func (store *MongoDBStore) FuzzySearchLocations(query string, limit int64) ([]model.LocationSearchResult, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	terms := fuzzyNameTerms(query)
+	if len(terms) == 0 {
+		return []model.LocationSearchResult{}, nil
+	}
+
+	matches := bson.A{}
+
+	for _, term := range terms {
+		matches = append(matches, bson.M{"$cond": bson.A{
+			bson.M{"$regexMatch": bson.M{"input": "$name", "regex": term, "options": "i"}}, 1, 0,
+		}})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: activeFilter(bson.M{
+			"name": primitive.Regex{Pattern: strings.Join(terms, "|"), Options: "i"},
+		})}},
+		{{Key: "$addFields", Value: bson.M{fuzzyMatchesField: bson.M{"$add": matches}}}},
+		{{Key: "$match", Value: bson.M{fuzzyMatchesField: bson.M{"$gte": fuzzyMinMatches(len(terms))}}}},
+		{{Key: "$sort", Value: bson.D{{Key: fuzzyMatchesField, Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$unset", Value: fuzzyMatchesField}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locations := []model.LocationSearchResult{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// fuzzyNameTerms turns the trigrams of query, as helper.Trigrams makes them,
+// into regular expressions for names, sorted. The padding spaces become word
+// boundaries, so the start and the end of a word are matched as such.
+func fuzzyNameTerms(query string) []string {
+	terms := []string{}
+
+	for trigram := range helper.Trigrams(query) {
+		word := strings.TrimLeft(trigram, " ")
+
+		term := regexp.QuoteMeta(strings.TrimRight(word, " "))
+		if len(word) < len(trigram) {
+			term = `\b` + term
+		}
+
+		if strings.HasSuffix(word, " ") {
+			term += `\b`
+		}
+
+		terms = append(terms, term)
+	}
+
+	sort.Strings(terms)
+
+	return terms
+}
+
+// fuzzyMinMatches is the fewest of terms trigrams a name has to contain. A name
+// with fewer shares less than minSearchRelevance of the query's trigrams, so its
+// similarity to the query can only be lower.
+func fuzzyMinMatches(terms int) int {
+	minMatches := int(minSearchRelevance * float64(terms))
+	if float64(minMatches)/float64(terms) < minSearchRelevance {
+		minMatches++
+	}
+
+	return max(minMatches, 1)
+}
+
+// geoNearStage sorts active documents nearest first from the given point and
+// writes the distance in kilometres to the distance field. Zero bounds are left out.
+func geoNearStage(latitude, longitude, minDistanceKm, maxDistanceKm float64) bson.D {
+	geoNear := bson.D{
+		{Key: "near", Value: geoPoint(latitude, longitude)},
+		{Key: "distanceField", Value: "distance"},
+		{Key: "distanceMultiplier", Value: 1.0 / metersPerKilometer},
+		{Key: "spherical", Value: true},
+		{Key: "query", Value: activeFilter(bson.M{})},
+	}
+
+	if maxDistanceKm > 0 {
+		geoNear = append(geoNear, bson.E{Key: "maxDistance", Value: maxDistanceKm * metersPerKilometer})
+	}
+
+	if minDistanceKm > 0 {
+		geoNear = append(geoNear, bson.E{Key: "minDistance", Value: minDistanceKm * metersPerKilometer})
+	}
+
+	return bson.D{{Key: "$geoNear", Value: geoNear}}
+}
+
+func (store *MongoDBStore) GetLocationsInBox(req *model.GetLocationsInBoxRequest) (*model.GetLocationsResponse, error) {
+	filter := bson.M{
+		"latitude": bson.M{"$gte": *req.MinLatitude, "$lte": *req.MaxLatitude},
+	}
+
+	// A box whose western edge is east of its eastern edge crosses the
+	// antimeridian, so it is split into the two longitude ranges on either side.
+	if *req.MinLongitude <= *req.MaxLongitude {
+		filter["longitude"] = bson.M{"$gte": *req.MinLongitude, "$lte": *req.MaxLongitude}
+	} else {
+		filter["$or"] = bson.A{
+			bson.M{"longitude": bson.M{"$gte": *req.MinLongitude, "$lte": 180}},
+			bson.M{"longitude": bson.M{"$gte": -180, "$lte": *req.MaxLongitude}},
+		}
+	}
+
+	return store.findLocations(filter, req.Page, req.Limit)
+}
+
+func (store *MongoDBStore) GetLocationsInPolygon(req *model.GetLocationsInPolygonRequest) (*model.GetLocationsResponse, error) {
+	filter := bson.M{
+		geoField: bson.M{"$geoWithin": bson.M{"$geometry": bson.M{
+			"type":        req.Type,
+			"coordinates": req.Coordinates,
+		}}},
+	}
+
+	return store.findLocations(filter, req.Page, req.Limit)
+}
+
+// findLocations returns one page of the locations matching filter ordered by
+// _id, so pages stay stable while the filter is unchanged.
+func (store *MongoDBStore) findLocations(filter bson.M, page, limit int) (*model.GetLocationsResponse, error) {
+	collection := store.Client.Database("location").Collection("locations")
+
+	skip, pageLimit := paginate(page, limit)
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetSkip(skip).SetLimit(pageLimit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), store.timeout)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, activeFilter(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	locations := []model.GetLocationResponse{}
+	if err := cursor.All(ctx, &locations); err != nil {
+		return nil, err
+	}
+
+	return &model.GetLocationsResponse{Locations: locations}, nil
+}
+
+// paginate converts a one-based page and a page size into skip and limit values,
+// falling back to the first page and the default page size.
+func paginate(page, limit int) (skip, pageLimit int64) {
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 {
+		limit = defaultPageLimit
+	}
 
-	return &model.GetAllLocationsDBResponse{Locations: locations}, nil
+	return int64(page-1) * int64(limit), int64(limit)
 }
@@ -2,10 +2,15 @@ package internal
 
 import (
 	"context"
-	"location-api/internal/helper"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"location-api/internal/cache"
 	"location-api/model"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/testcontainers/testcontainers-go"
@@ -32,9 +37,13 @@ func NewStoreWithURI(uri string) *MongoDBStore {
 		log.Fatal("Unable to access MongoDB:", err)
 	}
 
-	return &MongoDBStore{
-		Client: client,
+	store := newStore(client, cache.NewMemoryCache(0))
+
+	if err = store.ensureIndexes(); err != nil {
+		log.Fatal("Unable to create indexes:", err)
 	}
+
+	return store
 }
 
 func prepareTestStore(t *testing.T) (store *MongoDBStore, clean func()) {
@@ -72,20 +81,17 @@ func TestMongoDBStore_GetRoutes(t *testing.T) {
 		store, clean := prepareTestStore(t)
 		defer clean()
 
-		_ = helper.DeleteCache(cacheKey)
+		_ = store.Cache.DeleteByPrefix(cacheKey)
 
-		_ = store.Client.Database("location").Collection("locations").Drop(context.Background())
-
-		resp, err := store.GetRoutes()
-		if err == nil {
-			t.Fatalf("Expected error, but got nil")
+		resp, err := store.GetRoutes(&testGetRoutesReq)
+		if err != nil {
+			t.Fatalf("Failed to get routes: %v", err)
 		}
 
-		assert.Nil(t, resp)
-		assert.Equal(t, mongo.ErrNoDocuments, err)
+		assert.Empty(t, resp.Routes)
 	})
 
-	t.Run("should get routes", func(t *testing.T) {
+	t.Run("should get routes sorted by distance", func(t *testing.T) {
 		store, clean := prepareTestStore(t)
 		defer clean()
 
@@ -93,15 +99,17 @@ func TestMongoDBStore_GetRoutes(t *testing.T) {
 		locationDocs := []interface{}{
 			bson.M{
 				"name":         "test3",
-				"latitude":     124.12,
-				"longitude":    134.12,
+				"latitude":     41.0082,
+				"longitude":    28.9784,
 				"marker_color": "FFFAFF",
+				"location":     geoPoint(41.0082, 28.9784),
 			},
 			bson.M{
 				"name":         "test5",
-				"latitude":     125.12,
-				"longitude":    135.12,
+				"latitude":     39.9334,
+				"longitude":    32.8597,
 				"marker_color": "000000",
+				"location":     geoPoint(39.9334, 32.8597),
 			},
 		}
 
@@ -110,16 +118,69 @@ func TestMongoDBStore_GetRoutes(t *testing.T) {
 			t.Fatalf("Failed to insert locations: %v", err)
 		}
 
-		resp, err := store.GetRoutes()
+		resp, err := store.GetRoutes(&model.GetRoutesRequest{Latitude: float64Ptr(40.0), Longitude: float64Ptr(32.0)})
 		if err != nil {
 			t.Fatalf("Failed to get routes: %v", err)
 		}
 
-		if len(resp.Locations) == 0 {
-			t.Fatalf("Expected routes to be returned, got empty slice")
+		if len(resp.Routes) != 2 {
+			t.Fatalf("Expected 2 routes, got %d", len(resp.Routes))
 		}
 
-		t.Logf("Got %d routes", len(resp.Locations))
+		assert.Equal(t, "test5", resp.Routes[0].Name)
+		assert.Less(t, resp.Routes[0].Distance, resp.Routes[1].Distance)
+	})
+
+	t.Run("should apply limit and distance bounds", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		collection := store.Client.Database("location").Collection("locations")
+		locationDocs := []interface{}{
+			bson.M{
+				"name":         "near",
+				"latitude":     40.01,
+				"longitude":    32.01,
+				"marker_color": "FFFAFF",
+				"location":     geoPoint(40.01, 32.01),
+			},
+			bson.M{
+				"name":         "middle",
+				"latitude":     40.5,
+				"longitude":    32.5,
+				"marker_color": "000000",
+				"location":     geoPoint(40.5, 32.5),
+			},
+			bson.M{
+				"name":         "far",
+				"latitude":     45.0,
+				"longitude":    35.0,
+				"marker_color": "000000",
+				"location":     geoPoint(45.0, 35.0),
+			},
+		}
+
+		_, err := collection.InsertMany(context.Background(), locationDocs)
+		if err != nil {
+			t.Fatalf("Failed to insert locations: %v", err)
+		}
+
+		resp, err := store.GetRoutes(&model.GetRoutesRequest{
+			Latitude:    float64Ptr(40.0),
+			Longitude:   float64Ptr(32.0),
+			Limit:       1,
+			MinDistance: 10,
+			MaxDistance: 500,
+		})
+		if err != nil {
+			t.Fatalf("Failed to get routes: %v", err)
+		}
+
+		if len(resp.Routes) != 1 {
+			t.Fatalf("Expected 1 route, got %d", len(resp.Routes))
+		}
+
+		assert.Equal(t, "middle", resp.Routes[0].Name)
 	})
 }
 
@@ -147,6 +208,35 @@ func TestMongoDBStore_CreateLocation(t *testing.T) {
 	})
 }
 
+func TestMongoDBStore_CreateLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should insert every location", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		resp, err := store.CreateLocations(&model.CreateLocationsRequest{
+			Locations: []model.CreateLocationRequest{
+				{Name: "first", Latitude: float64Ptr(40.5), Longitude: float64Ptr(32.5), MarkerColor: "FFFFFF"},
+				{Name: "second", Latitude: float64Ptr(45.0), Longitude: float64Ptr(35.0), MarkerColor: "000000"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create locations: %v", err)
+		}
+
+		assert.Len(t, resp.CreatedIDs, 2)
+		assert.Empty(t, resp.Failed)
+		assert.Equal(t, int64(2), resp.CreatedCount)
+
+		location, err := store.GetLocation(&model.GetLocationRequest{ID: resp.CreatedIDs[1]})
+		assert.Nil(t, err)
+		assert.Equal(t, "second", location.Name)
+	})
+}
+
 func TestMongoDBStore_GetLocation(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -211,24 +301,227 @@ func TestMongoDBStore_GetLocation(t *testing.T) {
 	})
 }
 
+func TestMongoDBStore_GetLocationHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should record every change and rebuild a location as of a time", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		created, err := store.CreateLocation(&model.CreateLocationRequest{
+			Name:        "test",
+			Latitude:    float64Ptr(40.5),
+			Longitude:   float64Ptr(32.5),
+			MarkerColor: "FFFFFF",
+			Actor:       "jane",
+		})
+		if err != nil {
+			t.Fatalf("Failed to create location: %v", err)
+		}
+
+		// Stored timestamps have millisecond precision, so the steps are spaced out
+		// to keep them apart.
+		time.Sleep(10 * time.Millisecond)
+		beforeMove := time.Now()
+		time.Sleep(10 * time.Millisecond)
+
+		_, err = store.UpdateLocations(&model.UpdateLocationsRequest{
+			Locations: []model.UpdateLocation{{ID: created.ID, Latitude: float64Ptr(41)}},
+			Actor:     "joe",
+		})
+		if err != nil {
+			t.Fatalf("Failed to update location: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		_, err = store.DeleteLocation(&model.DeleteLocationRequest{ID: created.ID})
+		if err != nil {
+			t.Fatalf("Failed to delete location: %v", err)
+		}
+
+		res, err := store.GetLocationHistory(&model.GetLocationHistoryRequest{ID: created.ID})
+		assert.Nil(t, err)
+		assert.Len(t, res.History, 3)
+
+		deleted, moved, first := res.History[0], res.History[1], res.History[2]
+
+		assert.Equal(t, model.HistoryActionCreate, first.Action)
+		assert.Equal(t, "jane", first.Actor)
+		assert.Nil(t, first.Before)
+		assert.Equal(t, &model.LocationState{Name: "test", Latitude: 40.5, Longitude: 32.5, MarkerColor: "FFFFFF"}, first.After)
+
+		assert.Equal(t, model.HistoryActionUpdate, moved.Action)
+		assert.Equal(t, "joe", moved.Actor)
+		assert.Equal(t, int64(2), moved.Version)
+		assert.Equal(t, 40.5, moved.Before.Latitude)
+		assert.Equal(t, 41.0, moved.After.Latitude)
+
+		assert.Equal(t, model.HistoryActionDelete, deleted.Action)
+		assert.Equal(t, "anonymous", deleted.Actor)
+		assert.Nil(t, deleted.After)
+
+		location, err := store.GetLocationAsOf(created.ID, beforeMove)
+		assert.Nil(t, err)
+		assert.Equal(t, 40.5, location.Latitude)
+		assert.Equal(t, int64(1), location.Version)
+
+		_, err = store.GetLocationAsOf(created.ID, time.Now())
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+
+		_, err = store.GetLocationAsOf(created.ID, beforeMove.Add(-time.Hour))
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+}
+
+func TestMongoDBStore_RevertLocation(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should roll back an update and record the revert", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		created, err := store.CreateLocation(&model.CreateLocationRequest{
+			Name:        "test",
+			Latitude:    float64Ptr(40.5),
+			Longitude:   float64Ptr(32.5),
+			MarkerColor: "FFFFFF",
+		})
+		if err != nil {
+			t.Fatalf("Failed to create location: %v", err)
+		}
+
+		_, err = store.UpdateLocations(&model.UpdateLocationsRequest{
+			Locations: []model.UpdateLocation{{ID: created.ID, Name: "renamed", Latitude: float64Ptr(0)}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to update location: %v", err)
+		}
+
+		version := int64(1)
+
+		reverted, err := store.RevertLocation(&model.RevertLocationRequest{ID: created.ID, Version: &version, Actor: "jane"})
+		assert.Nil(t, err)
+		assert.Equal(t, "test", reverted.Name)
+		assert.Equal(t, 40.5, reverted.Latitude)
+		assert.Equal(t, int64(3), reverted.Version)
+
+		location, err := store.GetLocation(&model.GetLocationRequest{ID: created.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, "test", location.Name)
+		assert.Equal(t, 40.5, location.Latitude)
+		assert.Equal(t, int64(3), location.Version)
+
+		history, err := store.GetLocationHistory(&model.GetLocationHistoryRequest{ID: created.ID})
+		assert.Nil(t, err)
+		assert.Equal(t, model.HistoryActionRevert, history.History[0].Action)
+		assert.Equal(t, "renamed", history.History[0].Before.Name)
+
+		missing := int64(9)
+
+		_, err = store.RevertLocation(&model.RevertLocationRequest{ID: created.ID, Version: &missing})
+		assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	})
+}
+
 func TestMongoDBStore_GetLocations(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
 	}
 
-	t.Run("should return no locations found", func(t *testing.T) {
+	t.Run("should return an empty page when there are no locations", func(t *testing.T) {
 		store, clean := prepareTestStore(t)
 		defer clean()
 
 		req := &model.GetLocationsRequest{}
 
 		resp, err := store.GetLocations(req)
-		if err == nil {
-			t.Fatalf("Expected error, but got nil")
+		assert.Nil(t, err)
+		assert.Equal(t, []model.GetLocationResponse{}, resp.Locations)
+		assert.Empty(t, resp.NextCursor)
+	})
+
+	t.Run("should page with cursors without repeating locations created in between", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store,
+			testLocationDoc("first", 40.5, 32.5),
+			testLocationDoc("second", 41, 33),
+			testLocationDoc("third", 41.5, 33.5),
+		)
+
+		first, err := store.GetLocations(&model.GetLocationsRequest{Limit: 2, Total: true})
+		assert.Nil(t, err)
+		assert.Len(t, first.Locations, 2)
+		assert.Equal(t, insertedIDs[0], first.Locations[0].ID)
+		assert.NotEmpty(t, first.NextCursor)
+		assert.Equal(t, int64(3), *first.Total)
+
+		insertTestLocations(t, store, testLocationDoc("fourth", 42, 34))
+
+		second, err := store.GetLocations(&model.GetLocationsRequest{Limit: 2, Cursor: first.NextCursor})
+		assert.Nil(t, err)
+		assert.Len(t, second.Locations, 2)
+		assert.Equal(t, insertedIDs[2], second.Locations[0].ID)
+		assert.Nil(t, second.Total)
+
+		last, err := store.GetLocations(&model.GetLocationsRequest{Limit: 2, Cursor: second.NextCursor})
+		assert.Nil(t, err)
+		assert.Empty(t, last.Locations)
+		assert.Empty(t, last.NextCursor)
+
+		_, err = store.GetLocations(&model.GetLocationsRequest{Cursor: "not a cursor"})
+		assert.ErrorIs(t, err, model.ErrInvalidCursor)
+	})
+
+	t.Run("should filter, sort and project while paging", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		blue := testLocationDoc("Harbour", 40.5, 32.5)
+		blue["marker_color"] = "0000FF"
+
+		insertTestLocations(t, store,
+			testLocationDoc("Harbour Gate", 41, 33),
+			testLocationDoc("Old Harbour", 41.5, 33.5),
+			testLocationDoc("harbour view", 42, 34),
+			testLocationDoc("Market", 42.5, 34.5),
+			blue,
+		)
+
+		req := &model.GetLocationsRequest{
+			NameContains: "harbour",
+			MarkerColor:  "FFFFFF",
+			Sort:         "-name",
+			Fields:       "name",
+			Limit:        2,
+			Total:        true,
 		}
 
-		assert.Nil(t, resp)
-		assert.Equal(t, mongo.ErrNoDocuments, err)
+		first, err := store.GetLocations(req)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), *first.Total)
+		assert.Equal(t, "harbour view", first.Locations[0].Name)
+		assert.Equal(t, "Old Harbour", first.Locations[1].Name)
+		assert.Equal(t, 0.0, first.Locations[0].Latitude)
+
+		req.Cursor = first.NextCursor
+		req.Total = false
+
+		second, err := store.GetLocations(req)
+		assert.Nil(t, err)
+		assert.Len(t, second.Locations, 1)
+		assert.Equal(t, "Harbour Gate", second.Locations[0].Name)
+		assert.Empty(t, second.NextCursor)
+
+		prefixed, err := store.GetLocations(&model.GetLocationsRequest{NamePrefix: "harbour"})
+		assert.Nil(t, err)
+		assert.Len(t, prefixed.Locations, 3)
 	})
 	t.Run("should get locations", func(t *testing.T) {
 		store, clean := prepareTestStore(t)
@@ -262,6 +555,93 @@ func TestMongoDBStore_GetLocations(t *testing.T) {
 	})
 }
 
+func TestLocationsFilter(t *testing.T) {
+	t.Run("should use names only as literal text", func(t *testing.T) {
+		filter, err := locationsFilter(&model.GetLocationsRequest{NamePrefix: "a.*", NameContains: "$ne"})
+		assert.Nil(t, err)
+		assert.Equal(t, bson.A{
+			bson.M{"name": primitive.Regex{Pattern: `^a\.\*`, Options: "i"}},
+			bson.M{"name": primitive.Regex{Pattern: `\$ne`, Options: "i"}},
+		}, filter["$and"])
+	})
+
+	t.Run("should bound times on both ends", func(t *testing.T) {
+		filter, err := locationsFilter(&model.GetLocationsRequest{
+			CreatedAfter:  "2025-03-01T00:00:00Z",
+			UpdatedBefore: "2025-03-31T00:00:00Z",
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, bson.M{"$gte": time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}, filter["created_at"])
+		assert.Equal(t, bson.M{"$lte": time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)}, filter["updated_at"])
+	})
+}
+
+func TestRoutesCacheKey(t *testing.T) {
+	near := roundRoutesRequest(&model.GetRoutesRequest{Latitude: float64Ptr(41.008212), Longitude: float64Ptr(28.978404), Limit: 3})
+	other := roundRoutesRequest(&model.GetRoutesRequest{Latitude: float64Ptr(41.00819), Longitude: float64Ptr(28.97839), Limit: 3})
+
+	assert.Equal(t, 41.0082, *near.Latitude)
+	assert.Equal(t, 28.9784, *near.Longitude)
+	assert.Equal(t, cacheKey+":41.0082:28.9784:3:0:0", routesCacheKey(near))
+	assert.Equal(t, routesCacheKey(near), routesCacheKey(other))
+}
+
+func TestLocationsCursor(t *testing.T) {
+	last := &model.GetLocationResponse{ID: "67d562e3d9f2d225ca4d9918", Name: "test"}
+
+	cursor, err := encodeCursor("-name", last, "name")
+	assert.Nil(t, err)
+
+	position, err := decodeCursor(cursor, "-name")
+	assert.Nil(t, err)
+	assert.Equal(t, "test", position.Value)
+	assert.Equal(t, last.ID, position.ID.Hex())
+
+	_, err = decodeCursor(cursor, "name")
+	assert.ErrorIs(t, err, model.ErrInvalidCursor)
+
+	_, err = decodeCursor("not a cursor", "")
+	assert.ErrorIs(t, err, model.ErrInvalidCursor)
+
+	tampered, err := hex.DecodeString("2c000000027300050000006e616d65000276930400000061926300076964006ad3cf7d8c9ef3f70e5de42f00")
+	assert.Nil(t, err)
+
+	_, err = decodeCursor(base64.RawURLEncoding.EncodeToString(tampered), "name")
+	assert.ErrorIs(t, err, model.ErrInvalidCursor)
+}
+
+func TestMongoDBStore_StreamLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should stream active locations in id order", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store,
+			testLocationDoc("first", 40.5, 32.5),
+			testLocationDoc("second", 45.0, 35.0),
+			testLocationDoc("archived", 41.0, 33.0),
+		)
+
+		_, err := store.ArchiveLocations(&model.ArchiveLocationsRequest{IDs: insertedIDs[2:]})
+		if err != nil {
+			t.Fatalf("Failed to archive locations: %v", err)
+		}
+
+		streamedIDs := []string{}
+
+		err = store.StreamLocations(func(location *model.GetLocationResponse) error {
+			streamedIDs = append(streamedIDs, location.ID)
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, insertedIDs[:2], streamedIDs)
+	})
+}
+
 func TestMongoDBStore_UpdateLocations(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -276,14 +656,14 @@ func TestMongoDBStore_UpdateLocations(t *testing.T) {
 		locationDocs := []interface{}{
 			bson.M{
 				"name":         "test3",
-				"latitude":     124.12,
-				"longitude":    134.12,
+				"latitude":     41.12,
+				"longitude":    34.12,
 				"marker_color": "FFFAFF",
 			},
 			bson.M{
 				"name":         "test5",
-				"latitude":     125.12,
-				"longitude":    135.12,
+				"latitude":     42.12,
+				"longitude":    35.12,
 				"marker_color": "000000",
 			},
 		}
@@ -309,15 +689,15 @@ func TestMongoDBStore_UpdateLocations(t *testing.T) {
 				{
 					ID:          insertedIDs[0],
 					Name:        "test4",
-					Latitude:    124.13,
-					Longitude:   134.13,
+					Latitude:    float64Ptr(41.13),
+					Longitude:   float64Ptr(34.13),
 					MarkerColor: "FFFAFE",
 				},
 				{
 					ID:          insertedIDs[1],
 					Name:        "test6",
-					Latitude:    125.13,
-					Longitude:   135.13,
+					Latitude:    float64Ptr(42.13),
+					Longitude:   float64Ptr(35.13),
 					MarkerColor: "111111",
 				},
 			},
@@ -336,6 +716,552 @@ func TestMongoDBStore_UpdateLocations(t *testing.T) {
 			t.Fatalf("Expected no failed IDs, but got: %v", resp.FailedIDs)
 		}
 
+		if resp.FailedIDs == nil || resp.ConflictIDs == nil {
+			t.Fatalf("Expected empty id lists, got failed %v and conflict %v", resp.FailedIDs, resp.ConflictIDs)
+		}
+
 		t.Logf("Updated locations with IDs: %v", resp.UpdatedIDs)
 	})
+
+	t.Run("should move a location onto the equator", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store, testLocationDoc("test", 40.5, 32.5))
+
+		resp, err := store.UpdateLocations(&model.UpdateLocationsRequest{
+			Locations: []model.UpdateLocation{{ID: insertedIDs[0], Latitude: float64Ptr(0)}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to update locations: %v", err)
+		}
+
+		assert.Equal(t, []string{insertedIDs[0]}, resp.UpdatedIDs)
+
+		location, err := store.GetLocation(&model.GetLocationRequest{ID: insertedIDs[0]})
+		assert.Nil(t, err)
+		assert.Equal(t, 0.0, location.Latitude)
+		assert.Equal(t, 32.5, location.Longitude)
+	})
+
+	t.Run("should bump the version and report stale expected versions as conflicts", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store, testLocationDoc("first", 40.5, 32.5), testLocationDoc("second", 41, 33))
+
+		expected := int64(1)
+
+		resp, err := store.UpdateLocations(&model.UpdateLocationsRequest{
+			Locations: []model.UpdateLocation{{ID: insertedIDs[0], Name: "renamed", ExpectedVersion: &expected}},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{insertedIDs[0]}, resp.UpdatedIDs)
+
+		location, err := store.GetLocation(&model.GetLocationRequest{ID: insertedIDs[0]})
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), location.Version)
+
+		resp, err = store.UpdateLocations(&model.UpdateLocationsRequest{
+			Locations: []model.UpdateLocation{
+				{ID: insertedIDs[0], Name: "stale", ExpectedVersion: &expected},
+				{ID: insertedIDs[1], Name: "fresh", ExpectedVersion: &expected},
+			},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{insertedIDs[1]}, resp.UpdatedIDs)
+		assert.Equal(t, []string{insertedIDs[0]}, resp.ConflictIDs)
+		assert.Empty(t, resp.FailedIDs)
+
+		location, err = store.GetLocation(&model.GetLocationRequest{ID: insertedIDs[0]})
+		assert.Nil(t, err)
+		assert.Equal(t, "renamed", location.Name)
+	})
+}
+
+func TestMongoDBStore_PatchLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should set present fields and remove null ones", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store, testLocationDoc("test", 40.5, 32.5))
+
+		var req model.PatchLocationsRequest
+
+		body := `{"locations": [` +
+			`{"id": "` + insertedIDs[0] + `", "name": "renamed", "latitude": 0, "marker_color": null},` +
+			`{"id": "5f9b1f3b1c9d440000f1b4b0", "name": "missing"}]}`
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatalf("Failed to decode patch: %v", err)
+		}
+
+		resp, err := store.PatchLocations(&req)
+		if err != nil {
+			t.Fatalf("Failed to patch locations: %v", err)
+		}
+
+		assert.Equal(t, []string{insertedIDs[0]}, resp.UpdatedIDs)
+		assert.Equal(t, []string{"5f9b1f3b1c9d440000f1b4b0"}, resp.FailedIDs)
+
+		location, err := store.GetLocation(&model.GetLocationRequest{ID: insertedIDs[0]})
+		assert.Nil(t, err)
+		assert.Equal(t, "renamed", location.Name)
+		assert.Equal(t, 0.0, location.Latitude)
+		assert.Equal(t, 32.5, location.Longitude)
+		assert.Equal(t, "", location.MarkerColor)
+
+		resp, err = store.PatchLocations(&req)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{insertedIDs[0]}, resp.UpdatedIDs)
+	})
+
+	t.Run("should report a stale expected version as a conflict", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store, testLocationDoc("test", 40.5, 32.5))
+
+		var req model.PatchLocationsRequest
+
+		body := `{"locations": [{"id": "` + insertedIDs[0] + `", "name": "renamed", "expected_version": 2}]}`
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatalf("Failed to decode patch: %v", err)
+		}
+
+		resp, err := store.PatchLocations(&req)
+		assert.Nil(t, err)
+		assert.Empty(t, resp.UpdatedIDs)
+		assert.Equal(t, []string{insertedIDs[0]}, resp.ConflictIDs)
+	})
+}
+
+func TestMongoDBStore_DeleteLocation(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should delete a location", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store, testLocationDoc("test", 40.5, 32.5))
+
+		resp, err := store.DeleteLocation(&model.DeleteLocationRequest{ID: insertedIDs[0]})
+		if err != nil {
+			t.Fatalf("Failed to delete location: %v", err)
+		}
+
+		assert.Equal(t, insertedIDs[0], resp.ID)
+
+		_, err = store.GetLocation(&model.GetLocationRequest{ID: insertedIDs[0]})
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+	})
+
+	t.Run("should return error document not found", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		resp, err := store.DeleteLocation(&model.DeleteLocationRequest{ID: "5f9b1f3b1c9d440000f1b4b0"})
+
+		assert.Nil(t, resp)
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+	})
+}
+
+func TestMongoDBStore_DeleteLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should report deleted and failed ids", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store,
+			testLocationDoc("first", 40.5, 32.5),
+			testLocationDoc("second", 45.0, 35.0),
+		)
+
+		resp, err := store.DeleteLocations(&model.DeleteLocationsRequest{
+			IDs: []string{insertedIDs[0], insertedIDs[1], "5f9b1f3b1c9d440000f1b4b0", "invalid"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to delete locations: %v", err)
+		}
+
+		assert.Equal(t, insertedIDs, resp.DeletedIDs)
+		assert.Equal(t, []string{"5f9b1f3b1c9d440000f1b4b0", "invalid"}, resp.FailedIDs)
+		assert.Equal(t, int64(2), resp.DeletedCount)
+	})
+}
+
+func TestMongoDBStore_ArchiveLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should hide archived locations until they are restored", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store,
+			testLocationDoc("first", 40.5, 32.5),
+			testLocationDoc("second", 45.0, 35.0),
+		)
+
+		archived, err := store.ArchiveLocations(&model.ArchiveLocationsRequest{
+			IDs: []string{insertedIDs[0], "5f9b1f3b1c9d440000f1b4b0"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to archive locations: %v", err)
+		}
+
+		assert.Equal(t, []string{insertedIDs[0]}, archived.ArchivedIDs)
+		assert.Equal(t, []string{"5f9b1f3b1c9d440000f1b4b0"}, archived.FailedIDs)
+
+		_, err = store.GetLocation(&model.GetLocationRequest{ID: insertedIDs[0]})
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+
+		archivedList, err := store.GetArchivedLocations(&model.GetArchivedLocationsRequest{})
+		if err != nil {
+			t.Fatalf("Failed to get archived locations: %v", err)
+		}
+
+		assert.Len(t, archivedList.Locations, 1)
+		assert.Equal(t, insertedIDs[0], archivedList.Locations[0].ID)
+
+		restored, err := store.RestoreLocations(&model.RestoreLocationsRequest{
+			IDs: []string{insertedIDs[0], insertedIDs[1]},
+		})
+		if err != nil {
+			t.Fatalf("Failed to restore locations: %v", err)
+		}
+
+		assert.Equal(t, []string{insertedIDs[0]}, restored.RestoredIDs)
+		assert.Equal(t, []string{insertedIDs[1]}, restored.FailedIDs)
+
+		location, err := store.GetLocation(&model.GetLocationRequest{ID: insertedIDs[0]})
+		assert.Nil(t, err)
+		assert.Equal(t, "first", location.Name)
+	})
+
+	t.Run("should purge locations archived before the cutoff", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store, testLocationDoc("first", 40.5, 32.5), testLocationDoc("second", 41, 33))
+
+		_, err := store.ArchiveLocations(&model.ArchiveLocationsRequest{IDs: insertedIDs})
+		if err != nil {
+			t.Fatalf("Failed to archive locations: %v", err)
+		}
+
+		purged, err := store.PurgeArchivedLocations(time.Now().Add(-time.Hour))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), purged)
+
+		purged, err = store.PurgeArchivedLocations(time.Now().Add(time.Minute))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), purged)
+
+		for _, id := range insertedIDs {
+			history, err := store.GetLocationHistory(&model.GetLocationHistoryRequest{ID: id})
+			assert.Nil(t, err)
+
+			if assert.NotEmpty(t, history.History) {
+				assert.Equal(t, model.HistoryActionPurge, history.History[0].Action)
+			}
+		}
+	})
+}
+
+func TestMongoDBStore_GetNearbyLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should return locations inside the radius nearest first", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		collection := store.Client.Database("location").Collection("locations")
+		locationDocs := []interface{}{
+			bson.M{
+				"name":         "middle",
+				"latitude":     40.5,
+				"longitude":    32.5,
+				"marker_color": "000000",
+				"location":     geoPoint(40.5, 32.5),
+			},
+			bson.M{
+				"name":         "near",
+				"latitude":     40.01,
+				"longitude":    32.01,
+				"marker_color": "FFFAFF",
+				"location":     geoPoint(40.01, 32.01),
+			},
+			bson.M{
+				"name":         "outside",
+				"latitude":     45.0,
+				"longitude":    35.0,
+				"marker_color": "000000",
+				"location":     geoPoint(45.0, 35.0),
+			},
+		}
+
+		_, err := collection.InsertMany(context.Background(), locationDocs)
+		if err != nil {
+			t.Fatalf("Failed to insert locations: %v", err)
+		}
+
+		resp, err := store.GetNearbyLocations(&model.GetNearbyLocationsRequest{
+			Latitude:  float64Ptr(40.0),
+			Longitude: float64Ptr(32.0),
+			RadiusKm:  100,
+		})
+		if err != nil {
+			t.Fatalf("Failed to get nearby locations: %v", err)
+		}
+
+		if len(resp.Locations) != 2 {
+			t.Fatalf("Expected 2 locations, got %d", len(resp.Locations))
+		}
+
+		assert.Equal(t, "near", resp.Locations[0].Name)
+		assert.Equal(t, "middle", resp.Locations[1].Name)
+
+		resp, err = store.GetNearbyLocations(&model.GetNearbyLocationsRequest{
+			Latitude:  float64Ptr(40.0),
+			Longitude: float64Ptr(32.0),
+			RadiusKm:  100,
+			Page:      2,
+			Limit:     1,
+		})
+		if err != nil {
+			t.Fatalf("Failed to get nearby locations: %v", err)
+		}
+
+		if len(resp.Locations) != 1 {
+			t.Fatalf("Expected 1 location, got %d", len(resp.Locations))
+		}
+
+		assert.Equal(t, "middle", resp.Locations[0].Name)
+	})
+}
+
+func TestFuzzyNameTerms(t *testing.T) {
+	assert.Equal(t, []string{`\bg`, `\bga`, `\bt`, `\bt\b`, `alt`, `gal`, `lta`, `ta\b`}, fuzzyNameTerms("Galta t."))
+	assert.Equal(t, []string{`\ba`, `\ban`, `ana`, `na\b`, `nan`}, fuzzyNameTerms("anana"))
+	assert.Equal(t, []string{`\ba`, `\ba\b`, `\bb`, `\bb\b`}, fuzzyNameTerms("a.*(b"))
+	assert.Empty(t, fuzzyNameTerms(" -- "))
+}
+
+func TestFuzzyMinMatches(t *testing.T) {
+	assert.Equal(t, 1, fuzzyMinMatches(1))
+	assert.Equal(t, 3, fuzzyMinMatches(7))
+	assert.Equal(t, 3, fuzzyMinMatches(10))
+}
+
+func TestMongoDBStore_SearchLocations(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should find names by word and by shared trigrams", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertTestLocations(t, store,
+			testLocationDoc("Galata Tower", 41.0256, 28.9741),
+			testLocationDoc("Maiden's Tower", 41.0211, 29.0041),
+			testLocationDoc("Moda Pier", 40.9816, 29.0250),
+		)
+
+		matches, err := store.TextSearchLocations("tower", 10)
+		assert.Nil(t, err)
+		assert.Len(t, matches, 2)
+		assert.Greater(t, matches[0].Score, 0.0)
+
+		candidates, err := store.FuzzySearchLocations("galta", 10)
+		assert.Nil(t, err)
+		assert.Len(t, candidates, 1)
+		assert.Equal(t, "Galata Tower", candidates[0].Name)
+	})
+
+	t.Run("should rank the names sharing the most trigrams first", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		docs := []bson.M{}
+		for i := 0; i < 250; i++ {
+			docs = append(docs, testLocationDoc(fmt.Sprintf("Gala %d", i), 41.0, 29.0))
+		}
+
+		docs = append(docs, testLocationDoc("Moda Pier", 40.9816, 29.0250), testLocationDoc("Galata Tower", 41.0256, 28.9741))
+		insertTestLocations(t, store, docs...)
+
+		candidates, err := store.FuzzySearchLocations("galata", 200)
+		assert.Nil(t, err)
+		assert.Len(t, candidates, 200)
+		assert.Equal(t, "Galata Tower", candidates[0].Name)
+		assert.Zero(t, candidates[0].Score)
+	})
+}
+
+func TestMongoDBStore_GetLocationsInBox(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should return locations inside the box", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertTestLocations(t, store,
+			testLocationDoc("inside", 40.5, 32.5),
+			testLocationDoc("outside", 45.0, 35.0),
+		)
+
+		resp, err := store.GetLocationsInBox(&model.GetLocationsInBoxRequest{
+			MinLatitude:  float64Ptr(40),
+			MinLongitude: float64Ptr(32),
+			MaxLatitude:  float64Ptr(41),
+			MaxLongitude: float64Ptr(33),
+		})
+		if err != nil {
+			t.Fatalf("Failed to get locations in box: %v", err)
+		}
+
+		if len(resp.Locations) != 1 {
+			t.Fatalf("Expected 1 location, got %d", len(resp.Locations))
+		}
+
+		assert.Equal(t, "inside", resp.Locations[0].Name)
+	})
+
+	t.Run("should handle boxes crossing the antimeridian", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertTestLocations(t, store,
+			testLocationDoc("fiji", -17.7, 178.0),
+			testLocationDoc("samoa", -13.8, -172.1),
+			testLocationDoc("greenwich", -15.0, 0.0),
+		)
+
+		resp, err := store.GetLocationsInBox(&model.GetLocationsInBoxRequest{
+			MinLatitude:  float64Ptr(-20),
+			MinLongitude: float64Ptr(170),
+			MaxLatitude:  float64Ptr(-10),
+			MaxLongitude: float64Ptr(-170),
+		})
+		if err != nil {
+			t.Fatalf("Failed to get locations in box: %v", err)
+		}
+
+		if len(resp.Locations) != 2 {
+			t.Fatalf("Expected 2 locations, got %d", len(resp.Locations))
+		}
+
+		assert.Equal(t, "fiji", resp.Locations[0].Name)
+		assert.Equal(t, "samoa", resp.Locations[1].Name)
+	})
+}
+
+func TestMongoDBStore_GetLocationsInPolygon(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should return locations inside the polygon", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertTestLocations(t, store,
+			testLocationDoc("inside", 1, 1),
+			testLocationDoc("outside", 3, 3),
+		)
+
+		resp, err := store.GetLocationsInPolygon(&testGetLocationsInPolygonReq)
+		if err != nil {
+			t.Fatalf("Failed to get locations in polygon: %v", err)
+		}
+
+		if len(resp.Locations) != 1 {
+			t.Fatalf("Expected 1 location, got %d", len(resp.Locations))
+		}
+
+		assert.Equal(t, "inside", resp.Locations[0].Name)
+	})
+}
+
+func testLocationDoc(name string, latitude, longitude float64) bson.M {
+	return bson.M{
+		"name":         name,
+		"latitude":     latitude,
+		"longitude":    longitude,
+		"marker_color": "FFFFFF",
+		"location":     geoPoint(latitude, longitude),
+		"version":      int64(1),
+	}
+}
+
+func insertTestLocations(t *testing.T, store *MongoDBStore, docs ...bson.M) []string {
+	t.Helper()
+
+	collection := store.Client.Database("location").Collection("locations")
+
+	locationDocs := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		locationDocs = append(locationDocs, doc)
+	}
+
+	result, err := collection.InsertMany(context.Background(), locationDocs)
+	if err != nil {
+		t.Fatalf("Failed to insert locations: %v", err)
+	}
+
+	insertedIDs := make([]string, 0, len(result.InsertedIDs))
+
+	for _, id := range result.InsertedIDs {
+		objectID, ok := id.(primitive.ObjectID)
+		if !ok {
+			t.Fatalf("Failed to convert inserted ID to ObjectID")
+		}
+
+		insertedIDs = append(insertedIDs, objectID.Hex())
+	}
+
+	return insertedIDs
+}
+
+func TestMongoDBStore_GetLocationsByIDs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+
+	t.Run("should return only existing locations", func(t *testing.T) {
+		store, clean := prepareTestStore(t)
+		defer clean()
+
+		insertedIDs := insertTestLocations(t, store,
+			testLocationDoc("first", 40.5, 32.5),
+			testLocationDoc("second", 45.0, 35.0),
+		)
+
+		resp, err := store.GetLocationsByIDs([]string{insertedIDs[0], "5f9b1f3b1c9d440000f1b4b0", "invalid"})
+		if err != nil {
+			t.Fatalf("Failed to get locations by IDs: %v", err)
+		}
+
+		if len(resp.Locations) != 1 {
+			t.Fatalf("Expected 1 location, got %d", len(resp.Locations))
+		}
+
+		assert.Equal(t, insertedIDs[0], resp.Locations[0].ID)
+	})
 }
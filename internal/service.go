@@ -1,25 +1,117 @@
 package internal
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"location-api/internal/cache"
+	"location-api/internal/encoder"
 	"location-api/internal/helper"
 	"location-api/model"
+	"log"
+	"math"
+	"net/url"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultExportFormat = "csv"
+
+// defaultTourLimit bounds the stops of a tour when no limit is given, as the
+// tour gets slower with every stop; routes sorted by distance return them all.
+const defaultTourLimit = 100
+
+// Single locations are cached under locationCacheKey and the pages of GET
+// /locations under locationsCacheKey and the current generation. A write drops
+// the generation, which orphans every cached page at once.
+const (
+	locationCacheKey           = "cached_location"
+	locationsCacheKey          = "cached_locations_page"
+	locationsGenerationKey     = "cached_locations_generation"
+	locationsGenerationTimeout = 24 * time.Hour
+)
+
+const (
+	defaultSearchLimit   = 10
+	searchCandidateLimit = 200
+	// minSearchRelevance drops fuzzy candidates that only share a trigram or two
+	// with the query.
+	minSearchRelevance = 0.3
+	// proximityWeight is the share of the score that distance can take away, and
+	// proximityScaleKm the distance at which half of it is lost.
+	proximityWeight  = 0.3
+	proximityScaleKm = 10.0
 )
 
 type Service struct {
-	store Store
+	store         Store
+	cache         cache.Cache
+	location      *cache.Loader
+	locations     *cache.Loader
+	cacheTTL      time.Duration
+	cacheStaleTTL time.Duration
 }
 
 type LocationDBStore interface {
 	CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error)
+	CreateLocations(req *model.CreateLocationsRequest) (*model.CreateLocationsResponse, error)
 	GetLocation(req *model.GetLocationRequest) (*model.GetLocationResponse, error)
+	GetLocationAsOf(id string, asOf time.Time) (*model.GetLocationResponse, error)
+	GetLocationHistory(req *model.GetLocationHistoryRequest) (*model.GetLocationHistoryResponse, error)
+	RevertLocation(req *model.RevertLocationRequest) (*model.GetLocationResponse, error)
 	GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error)
+	StreamLocations(fn func(location *model.GetLocationResponse) error) error
 	UpdateLocations(req *model.UpdateLocationsRequest) (*model.UpdateLocationsResponse, error)
-	GetRoutes() (*model.GetAllLocationsDBResponse, error)
+	PatchLocations(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error)
+	DeleteLocation(req *model.DeleteLocationRequest) (*model.DeleteLocationResponse, error)
+	DeleteLocations(req *model.DeleteLocationsRequest) (*model.DeleteLocationsResponse, error)
+	ArchiveLocations(req *model.ArchiveLocationsRequest) (*model.ArchiveLocationsResponse, error)
+	RestoreLocations(req *model.RestoreLocationsRequest) (*model.RestoreLocationsResponse, error)
+	GetArchivedLocations(req *model.GetArchivedLocationsRequest) (*model.GetArchivedLocationsResponse, error)
+	PurgeArchivedLocations(archivedBefore time.Time) (int64, error)
+	GetRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error)
+	GetLocationsByIDs(ids []string) (*model.GetLocationsResponse, error)
+	GetNearbyLocations(req *model.GetNearbyLocationsRequest) (*model.GetNearbyLocationsResponse, error)
+	TextSearchLocations(query string, limit int64) ([]model.LocationSearchResult, error)
+	FuzzySearchLocations(query string, limit int64) ([]model.LocationSearchResult, error)
+	GetLocationsInBox(req *model.GetLocationsInBoxRequest) (*model.GetLocationsResponse, error)
+	GetLocationsInPolygon(req *model.GetLocationsInPolygonRequest) (*model.GetLocationsResponse, error)
+}
+
+// ServiceOption customises a Service created by NewService.
+type ServiceOption func(*Service)
+
+// WithLocationsCache sets how long cached locations and pages of locations are
+// fresh and how long after that they are still served while being refreshed. A
+// ttl below one keeps the defaults.
+func WithLocationsCache(ttl, staleTTL time.Duration) ServiceOption {
+	return func(s *Service) {
+		if ttl > 0 {
+			s.cacheTTL = ttl
+			s.cacheStaleTTL = max(staleTTL, 0)
+		}
+	}
 }
 
-func NewService(s Store) *Service {
-	return &Service{store: s}
+func NewService(s Store, c cache.Cache, opts ...ServiceOption) *Service {
+	service := &Service{
+		store:         s,
+		cache:         c,
+		cacheTTL:      cacheDuration,
+		cacheStaleTTL: cacheStaleDuration,
+	}
+
+	for _, opt := range opts {
+		opt(service)
+	}
+
+	service.location = cache.NewLoader(c, "location", service.cacheTTL, service.cacheStaleTTL)
+	service.locations = cache.NewLoader(c, "locations", service.cacheTTL, service.cacheStaleTTL)
+
+	return service
 }
 
 func (s *Service) CreateLocation(req *model.CreateLocationRequest) (*model.CreateLocationResponse, error) {
@@ -28,17 +120,285 @@ func (s *Service) CreateLocation(req *model.CreateLocationRequest) (*model.Creat
 		return nil, err
 	}
 
-	_ = helper.DeleteCache("cached_db_locations")
+	s.invalidate()
+
+	return res, nil
+}
+
+// CreateLocations validates every location on its own, inserts the valid ones in
+// one batch and reports the rest by their index in the request.
+func (s *Service) CreateLocations(req *model.CreateLocationsRequest) (*model.CreateLocationsResponse, error) {
+	failed := []model.CreateLocationError{}
+	valid := &model.CreateLocationsRequest{Locations: make([]model.CreateLocationRequest, 0, len(req.Locations)), Actor: req.Actor}
+	validIndexes := make([]int, 0, len(req.Locations))
+
+	for i := range req.Locations {
+		if err := req.Locations[i].ValidateLocation(); err != nil {
+			failed = append(failed, model.CreateLocationError{Index: i, Error: err.Error()})
+			continue
+		}
+
+		valid.Locations = append(valid.Locations, req.Locations[i])
+		validIndexes = append(validIndexes, i)
+	}
+
+	if len(valid.Locations) == 0 {
+		return &model.CreateLocationsResponse{CreatedIDs: []string{}, Failed: failed}, nil
+	}
+
+	res, err := s.store.CreateLocations(valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range res.Failed {
+		failed = append(failed, model.CreateLocationError{Index: validIndexes[item.Index], Error: item.Error})
+	}
+
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Index < failed[j].Index })
+	res.Failed = failed
+
+	if len(res.CreatedIDs) > 0 {
+		s.invalidate()
+	}
 
 	return res, nil
 }
 
+// GetLocation returns the location as it is now or, with as_of, as the history
+// says it was at that time.
 func (s *Service) GetLocation(req *model.GetLocationRequest) (*model.GetLocationResponse, error) {
-	return s.store.GetLocation(req)
+	if req.AsOf == "" {
+		var location model.GetLocationResponse
+
+		err := s.location.Load(locationCacheKey+":"+req.ID, &location, func() (interface{}, error) {
+			return s.store.GetLocation(req)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return &location, nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339, req.AsOf)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.store.GetLocationAsOf(req.ID, asOf)
+}
+
+func (s *Service) GetLocationHistory(req *model.GetLocationHistoryRequest) (*model.GetLocationHistoryResponse, error) {
+	return s.store.GetLocationHistory(req)
+}
+
+func (s *Service) RevertLocation(req *model.RevertLocationRequest) (*model.GetLocationResponse, error) {
+	res, err := s.store.RevertLocation(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(req.ID)
+
+	return res, nil
 }
 
 func (s *Service) GetLocations(req *model.GetLocationsRequest) (*model.GetLocationsResponse, error) {
-	return s.store.GetLocations(req)
+	var locations model.GetLocationsResponse
+
+	key := locationsCacheKey + ":" + s.locationsGeneration() + ":" + locationsQueryKey(req)
+
+	err := s.locations.Load(key, &locations, func() (interface{}, error) {
+		return s.store.GetLocations(req)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &locations, nil
+}
+
+// locationsGeneration returns the generation the cached pages of GET /locations
+// belong to, starting a new one when it was dropped. A random generation rather
+// than a counter means two writers never agree on a generation that a reader
+// already filled with what it read before one of them.
+func (s *Service) locationsGeneration() string {
+	var generation string
+	if err := s.cache.Get(locationsGenerationKey, &generation); err == nil && generation != "" {
+		return generation
+	}
+
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	generation = hex.EncodeToString(b)
+
+	_ = s.cache.Set(locationsGenerationKey, generation, locationsGenerationTimeout)
+
+	return generation
+}
+
+// locationsQueryKey writes the parameters of req in a fixed order with defaults
+// filled in, so that requests for the same page share a cache key.
+func locationsQueryKey(req *model.GetLocationsRequest) string {
+	query := url.Values{}
+
+	if req.Cursor != "" {
+		query.Set("cursor", req.Cursor)
+	} else {
+		query.Set("page", strconv.Itoa(max(req.Page, 1)))
+	}
+
+	limit := req.Limit
+	if limit < 1 {
+		limit = defaultPageLimit
+	}
+
+	query.Set("limit", strconv.Itoa(limit))
+
+	if req.Total {
+		query.Set("total", "true")
+	}
+
+	for param, value := range map[string]string{
+		"name_prefix":   req.NamePrefix,
+		"name_contains": req.NameContains,
+		"marker_color":  req.MarkerColor,
+		"sort":          req.Sort,
+	} {
+		if value != "" {
+			query.Set(param, value)
+		}
+	}
+
+	for param, value := range map[string]string{
+		"created_after":  req.CreatedAfter,
+		"created_before": req.CreatedBefore,
+		"updated_after":  req.UpdatedAfter,
+		"updated_before": req.UpdatedBefore,
+	} {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			query.Set(param, t.UTC().Format(time.RFC3339Nano))
+		}
+	}
+
+	if fields := req.SelectedFields(); len(fields) > 0 {
+		sort.Strings(fields)
+		query.Set("fields", strings.Join(fields, ","))
+	}
+
+	return query.Encode()
+}
+
+// invalidate drops what a write made stale: the cached routes, every cached
+// page of GET /locations and the cached locations of ids. Loads of these keys
+// that are still running are not cached either, as they may have read the
+// location before the write and would bring its old version and ETag back.
+func (s *Service) invalidate(ids ...string) {
+	_ = s.cache.DeleteByPrefix(cacheKey)
+	_ = s.cache.Delete(locationsGenerationKey)
+
+	for _, id := range ids {
+		_ = s.cache.Delete(locationCacheKey + ":" + id)
+	}
+}
+
+// CreateLocationsFromGeoJSON creates a location from every Point feature of the
+// collection. Failures are reported by the index of the feature.
+func (s *Service) CreateLocationsFromGeoJSON(req *model.ImportGeoJSONRequest) (*model.CreateLocationsResponse, error) {
+	failed := []model.CreateLocationError{}
+	locations := &model.CreateLocationsRequest{Locations: make([]model.CreateLocationRequest, 0, len(req.Features)), Actor: req.Actor}
+	featureIndexes := make([]int, 0, len(req.Features))
+
+	for i := range req.Features {
+		location, err := locationFromFeature(&req.Features[i])
+		if err != nil {
+			failed = append(failed, model.CreateLocationError{Index: i, Error: err.Error()})
+			continue
+		}
+
+		locations.Locations = append(locations.Locations, location)
+		featureIndexes = append(featureIndexes, i)
+	}
+
+	res, err := s.CreateLocations(locations)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range res.Failed {
+		failed = append(failed, model.CreateLocationError{Index: featureIndexes[item.Index], Error: item.Error})
+	}
+
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Index < failed[j].Index })
+	res.Failed = failed
+
+	return res, nil
+}
+
+// ImportLocations reads locations from CSV and creates the valid ones in one
+// batch. In a dry run it only validates them. Errors refer to CSV line numbers.
+func (s *Service) ImportLocations(req *model.ImportLocationsRequest, data io.Reader) (*model.ImportLocationsResponse, error) {
+	file, err := readLocationsCSV(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, failed := file.Rows, file.Failed
+	res := &model.ImportLocationsResponse{CreatedIDs: []string{}, IgnoredColumns: file.Ignored, DryRun: req.DryRun}
+
+	if req.DryRun {
+		for _, row := range rows {
+			if err := row.Location.ValidateLocation(); err != nil {
+				failed = append(failed, model.ImportLocationError{Line: row.Line, Error: err.Error()})
+				continue
+			}
+
+			res.ValidCount++
+		}
+	} else {
+		locations := &model.CreateLocationsRequest{Locations: make([]model.CreateLocationRequest, len(rows)), Actor: req.Actor}
+		for i, row := range rows {
+			locations.Locations[i] = row.Location
+		}
+
+		created, err := s.CreateLocations(locations)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range created.Failed {
+			failed = append(failed, model.ImportLocationError{Line: rows[item.Index].Line, Error: item.Error})
+		}
+
+		res.CreatedIDs = created.CreatedIDs
+		res.CreatedCount = created.CreatedCount
+		res.ValidCount = len(created.CreatedIDs)
+	}
+
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Line < failed[j].Line })
+	res.Failed = failed
+
+	return res, nil
+}
+
+// ExportLocations writes every active location to w in the requested format,
+// CSV by default.
+func (s *Service) ExportLocations(req *model.ExportLocationsRequest, w io.Writer) error {
+	enc, ok := encoder.Lookup(exportFormat(req))
+	if !ok {
+		return model.ErrUnsupportedFormat
+	}
+
+	return enc.EncodeLocations(w, s.store.StreamLocations)
+}
+
+func exportFormat(req *model.ExportLocationsRequest) string {
+	if req.Format == "" {
+		return defaultExportFormat
+	}
+
+	return req.Format
 }
 
 func (s *Service) UpdateLocations(req *model.UpdateLocationsRequest) (*model.UpdateLocationsResponse, error) {
@@ -47,55 +407,434 @@ func (s *Service) UpdateLocations(req *model.UpdateLocationsRequest) (*model.Upd
 		return nil, err
 	}
 
-	_ = helper.DeleteCache("cached_db_locations")
+	if len(res.UpdatedIDs) > 0 {
+		s.invalidate(res.UpdatedIDs...)
+	}
 
 	return res, nil
 }
 
+func (s *Service) PatchLocations(req *model.PatchLocationsRequest) (*model.UpdateLocationsResponse, error) {
+	res, err := s.store.PatchLocations(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.UpdatedIDs) > 0 {
+		s.invalidate(res.UpdatedIDs...)
+	}
+
+	return res, nil
+}
+
+func (s *Service) DeleteLocation(req *model.DeleteLocationRequest) (*model.DeleteLocationResponse, error) {
+	res, err := s.store.DeleteLocation(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(req.ID)
+
+	return res, nil
+}
+
+func (s *Service) DeleteLocations(req *model.DeleteLocationsRequest) (*model.DeleteLocationsResponse, error) {
+	res, err := s.store.DeleteLocations(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.DeletedIDs) > 0 {
+		s.invalidate(res.DeletedIDs...)
+	}
+
+	return res, nil
+}
+
+func (s *Service) ArchiveLocations(req *model.ArchiveLocationsRequest) (*model.ArchiveLocationsResponse, error) {
+	res, err := s.store.ArchiveLocations(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.ArchivedIDs) > 0 {
+		s.invalidate(res.ArchivedIDs...)
+	}
+
+	return res, nil
+}
+
+func (s *Service) RestoreLocations(req *model.RestoreLocationsRequest) (*model.RestoreLocationsResponse, error) {
+	res, err := s.store.RestoreLocations(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.RestoredIDs) > 0 {
+		s.invalidate(res.RestoredIDs...)
+	}
+
+	return res, nil
+}
+
+func (s *Service) GetArchivedLocations(req *model.GetArchivedLocationsRequest) (*model.GetArchivedLocationsResponse, error) {
+	return s.store.GetArchivedLocations(req)
+}
+
+// PurgeArchivedLocations permanently removes locations that have been archived
+// for longer than retention.
+func (s *Service) PurgeArchivedLocations(retention time.Duration) (int64, error) {
+	return s.store.PurgeArchivedLocations(time.Now().Add(-retention))
+}
+
+// RunArchivePurge purges archived locations older than retention every interval
+// until ctx is done.
+func (s *Service) RunArchivePurge(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeArchivedLocations(retention)
+			if err != nil {
+				log.Println("ERROR: archived locations cannot purge:", err)
+				continue
+			}
+
+			if purged > 0 {
+				log.Println("INFO: purged archived locations:", purged)
+			}
+		}
+	}
+}
+
 func (s *Service) GetRoutes(req *model.GetRoutesRequest) (*model.GetRoutesResponse, error) {
-	locationsResp, err := s.store.GetRoutes()
+	if req.Mode == model.RouteModeTour && req.Limit < 1 {
+		limited := *req
+		limited.Limit = defaultTourLimit
+		req = &limited
+	}
+
+	res, err := s.store.GetRoutes(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(locationsResp.Locations) == 0 {
-		return &model.GetRoutesResponse{Routes: []model.Route{}}, nil
+	if req.Mode != model.RouteModeTour {
+		return res, nil
 	}
 
-	if req.Latitude == 0 || req.Longitude == 0 {
+	return buildTour(helper.Point{Latitude: *req.Latitude, Longitude: *req.Longitude}, nil, res.Routes), nil
+}
+
+// PlanRoute orders the requested locations as a tour from the start coordinate,
+// optionally finishing at an end coordinate or back at the start. IDs that are
+// not found are reported in MissingIDs instead of failing the request.
+func (s *Service) PlanRoute(req *model.PlanRouteRequest) (*model.GetRoutesResponse, error) {
+	ids := make([]string, 0, len(req.LocationIDs))
+	seen := make(map[string]bool, len(req.LocationIDs))
+
+	for _, id := range req.LocationIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	locationsRes, err := s.store.GetLocationsByIDs(ids)
+	if err != nil {
 		return nil, err
 	}
 
-	type LocationDistance struct {
-		Location model.GetLocationResponse
-		Distance float64
+	found := make(map[string]model.GetLocationResponse, len(locationsRes.Locations))
+	for _, location := range locationsRes.Locations {
+		found[location.ID] = location
 	}
 
-	locationDistances := make([]LocationDistance, 0, len(locationsResp.Locations))
+	start := helper.Point{Latitude: *req.Start.Latitude, Longitude: *req.Start.Longitude}
+	routes := make([]model.Route, 0, len(found))
+
+	var missingIDs []string
 
-	for _, loc := range locationsResp.Locations {
-		distance := helper.Haversine(req.Latitude, req.Longitude, loc.Latitude, loc.Longitude)
-		locationDistances = append(locationDistances, LocationDistance{
-			Location: loc,
-			Distance: distance,
+	for _, id := range ids {
+		location, ok := found[id]
+		if !ok {
+			missingIDs = append(missingIDs, id)
+			continue
+		}
+
+		routes = append(routes, model.Route{
+			ID:          location.ID,
+			Name:        location.Name,
+			Latitude:    location.Latitude,
+			Longitude:   location.Longitude,
+			Distance:    helper.Haversine(start.Latitude, start.Longitude, location.Latitude, location.Longitude),
+			MarkerColor: location.MarkerColor,
 		})
 	}
 
-	sort.Slice(locationDistances, func(i, j int) bool {
-		return locationDistances[i].Distance < locationDistances[j].Distance
-	})
+	var end *helper.Point
 
-	sortedRoutes := make([]model.Route, 0, len(locationDistances))
-	for _, loc := range locationDistances {
-		sortedRoutes = append(sortedRoutes, model.Route{
-			ID:          loc.Location.ID,
-			Name:        loc.Location.Name,
-			Distance:    loc.Distance,
-			MarkerColor: loc.Location.MarkerColor,
-		})
+	switch {
+	case req.End != nil:
+		end = &helper.Point{Latitude: *req.End.Latitude, Longitude: *req.End.Longitude}
+	case req.ReturnToStart:
+		end = &start
+	}
+
+	res := buildTour(start, end, routes)
+	res.MissingIDs = missingIDs
+
+	return res, nil
+}
+
+// buildTour orders routes as a visiting tour from start, finishing at end when
+// it is given, and fills in the leg and cumulative distances along it.
+func buildTour(start helper.Point, end *helper.Point, routes []model.Route) *model.GetRoutesResponse {
+	stops := make([]helper.Point, 0, len(routes))
+	for _, route := range routes {
+		stops = append(stops, helper.Point{Latitude: route.Latitude, Longitude: route.Longitude})
+	}
+
+	var order []int
+	if end != nil {
+		order = helper.TourToEnd(start, *end, stops)
+	} else {
+		order = helper.Tour(start, stops)
+	}
+
+	tour := make([]model.Route, 0, len(routes))
+	previous := start
+
+	var total float64
+
+	for _, index := range order {
+		route := routes[index]
+		route.LegDistance = helper.Haversine(previous.Latitude, previous.Longitude, route.Latitude, route.Longitude)
+		total += route.LegDistance
+		route.CumulativeDistance = total
+
+		tour = append(tour, route)
+		previous = stops[index]
+	}
+
+	res := &model.GetRoutesResponse{Routes: tour, Start: &model.Coordinate{Latitude: &start.Latitude, Longitude: &start.Longitude}}
+
+	if end != nil {
+		res.End = &model.Coordinate{Latitude: &end.Latitude, Longitude: &end.Longitude}
+		res.FinalLegDistance = helper.Haversine(previous.Latitude, previous.Longitude, end.Latitude, end.Longitude)
+		total += res.FinalLegDistance
+	}
+
+	res.TotalDistance = total
+
+	return res
+}
+
+// GetDistanceMatrix resolves the origins and destinations given by ID to their
+// coordinates and returns the distance in kilometres between every pair. When an
+// ID cannot be found the response only carries MissingIDs.
+func (s *Service) GetDistanceMatrix(req *model.DistanceMatrixRequest) (*model.DistanceMatrixResponse, error) {
+	res, err := s.ResolveDistanceMatrix(req)
+	if err != nil || len(res.MissingIDs) > 0 {
+		return res, err
+	}
+
+	res.Distances = make([][]float64, 0, len(res.Origins))
+
+	for _, origin := range res.Origins {
+		res.Distances = append(res.Distances, distanceMatrixRow(origin, res.Destinations))
+	}
+
+	return res, nil
+}
+
+// ResolveDistanceMatrix is GetDistanceMatrix without the distances, for callers
+// that compute the rows one at a time with distanceMatrixRow.
+func (s *Service) ResolveDistanceMatrix(req *model.DistanceMatrixRequest) (*model.DistanceMatrixResponse, error) {
+	var ids []string
+
+	seen := map[string]bool{}
+
+	for _, points := range [][]model.MatrixPoint{req.Origins, req.Destinations} {
+		for _, point := range points {
+			if point.ID != "" && !seen[point.ID] {
+				seen[point.ID] = true
+				ids = append(ids, point.ID)
+			}
+		}
+	}
+
+	found := map[string]model.GetLocationResponse{}
+
+	if len(ids) > 0 {
+		locationsRes, err := s.store.GetLocationsByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, location := range locationsRes.Locations {
+			found[location.ID] = location
+		}
 	}
 
-	return &model.GetRoutesResponse{
-		Routes: sortedRoutes,
+	var missingIDs []string
+
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			missingIDs = append(missingIDs, id)
+		}
+	}
+
+	if len(missingIDs) > 0 {
+		return &model.DistanceMatrixResponse{MissingIDs: missingIDs}, nil
+	}
+
+	return &model.DistanceMatrixResponse{
+		Origins:      resolveMatrixPoints(req.Origins, found),
+		Destinations: resolveMatrixPoints(req.Destinations, found),
 	}, nil
 }
+
+// distanceMatrixRow returns the distances in kilometres from a resolved origin
+// to every resolved destination.
+func distanceMatrixRow(origin model.MatrixPoint, destinations []model.MatrixPoint) []float64 {
+	row := make([]float64, 0, len(destinations))
+	for _, destination := range destinations {
+		row = append(row, helper.Haversine(*origin.Latitude, *origin.Longitude, *destination.Latitude, *destination.Longitude))
+	}
+
+	return row
+}
+
+// resolveMatrixPoints fills in the coordinates of points given by ID.
+func resolveMatrixPoints(points []model.MatrixPoint, found map[string]model.GetLocationResponse) []model.MatrixPoint {
+	resolved := make([]model.MatrixPoint, 0, len(points))
+
+	for _, point := range points {
+		if point.ID != "" {
+			location := found[point.ID]
+			point.Latitude = &location.Latitude
+			point.Longitude = &location.Longitude
+		}
+
+		resolved = append(resolved, point)
+	}
+
+	return resolved
+}
+
+func (s *Service) GetNearbyLocations(req *model.GetNearbyLocationsRequest) (*model.GetNearbyLocationsResponse, error) {
+	return s.store.GetNearbyLocations(req)
+}
+
+// SearchLocations ranks locations by how well their names match the query. Text
+// index matches are scored relative to the best of them; when they do not fill
+// the page, names that share trigrams with the query are added, which catches
+// typos and half typed words. A name with a word that starts with the query
+// always ranks well, for autocomplete. With a position, closer locations are
+// moved up.
+func (s *Service) SearchLocations(req *model.SearchLocationsRequest) (*model.SearchLocationsResponse, error) {
+	query := strings.TrimSpace(req.Query)
+
+	limit := req.Limit
+	if limit < 1 {
+		limit = defaultSearchLimit
+	}
+
+	matches, err := s.store.TextSearchLocations(query, searchCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) < limit {
+		fuzzy, err := s.store.FuzzySearchLocations(query, searchCandidateLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, fuzzy...)
+	}
+
+	var bestTextScore float64
+
+	for _, match := range matches {
+		bestTextScore = math.Max(bestTextScore, match.Score)
+	}
+
+	results := []model.LocationSearchResult{}
+	seen := make(map[string]bool, len(matches))
+
+	for _, match := range matches {
+		if seen[match.ID] {
+			continue
+		}
+
+		seen[match.ID] = true
+
+		relevance := nameRelevance(query, match.Name)
+		if match.Score > 0 {
+			relevance = math.Max(relevance, match.Score/bestTextScore)
+		}
+
+		if relevance < minSearchRelevance {
+			continue
+		}
+
+		match.Score = relevance
+
+		if req.Latitude != nil {
+			distance := helper.Haversine(*req.Latitude, *req.Longitude, match.Latitude, match.Longitude)
+			match.Distance = &distance
+			match.Score *= 1 - proximityWeight + proximityWeight/(1+distance/proximityScaleKm)
+		}
+
+		results = append(results, match)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].Name < results[j].Name
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return &model.SearchLocationsResponse{Locations: results}, nil
+}
+
+// nameRelevance scores a name against the query by trigram similarity, and at
+// least as a prefix match when one of its words starts with the query. Longer
+// prefixes of shorter names score higher.
+func nameRelevance(query, name string) float64 {
+	relevance := helper.TrigramSimilarity(query, name)
+
+	lowerQuery, lowerName := strings.ToLower(query), strings.ToLower(name)
+	isPrefix := strings.HasPrefix(lowerName, lowerQuery)
+
+	for _, word := range strings.Fields(lowerName) {
+		isPrefix = isPrefix || strings.HasPrefix(word, lowerQuery)
+	}
+
+	if isPrefix {
+		prefix := 0.6 + 0.4*float64(len(lowerQuery))/float64(len(lowerName))
+		relevance = math.Max(relevance, prefix)
+	}
+
+	return relevance
+}
+
+func (s *Service) GetLocationsInBox(req *model.GetLocationsInBoxRequest) (*model.GetLocationsResponse, error) {
+	return s.store.GetLocationsInBox(req)
+}
+
+func (s *Service) GetLocationsInPolygon(req *model.GetLocationsInPolygonRequest) (*model.GetLocationsResponse, error) {
+	return s.store.GetLocationsInPolygon(req)
+}
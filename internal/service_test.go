@@ -1,8 +1,13 @@
 package internal
 
 import (
+	"bytes"
+	"encoding/json"
+	"location-api/internal/cache"
 	"location-api/model"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
@@ -11,8 +16,8 @@ import (
 
 var testCreateLocationReq = model.CreateLocationRequest{
 	Name:        "test",
-	Latitude:    1.1,
-	Longitude:   1.1,
+	Latitude:    float64Ptr(1.1),
+	Longitude:   float64Ptr(1.1),
 	MarkerColor: "FFFFFF",
 }
 
@@ -30,6 +35,7 @@ var testGetLocationRes = model.GetLocationResponse{
 	Latitude:    1.1,
 	Longitude:   1.1,
 	MarkerColor: "FFFFFF",
+	Version:     3,
 }
 
 var testGetLocationsReq = model.GetLocationsRequest{
@@ -54,15 +60,15 @@ var testUpdateLocationsReq = model.UpdateLocationsRequest{
 		{
 			ID:          "67d562e3d9f2d225ca4d9918",
 			Name:        "test",
-			Latitude:    1.1,
-			Longitude:   1.1,
+			Latitude:    float64Ptr(1.1),
+			Longitude:   float64Ptr(1.1),
 			MarkerColor: "FFFFFF",
 		},
 		{
 			ID:          "67d562e3d9f2d225ca4d9919",
 			Name:        "test2",
-			Latitude:    2.2,
-			Longitude:   2.2,
+			Latitude:    float64Ptr(2.2),
+			Longitude:   float64Ptr(2.2),
 			MarkerColor: "000000",
 		},
 	},
@@ -73,22 +79,22 @@ var testUpdateLocationsReqForPartialContent = model.UpdateLocationsRequest{
 		{
 			ID:          "67d562e3d9f2d225ca4d9918",
 			Name:        "test",
-			Latitude:    1.1,
-			Longitude:   1.1,
+			Latitude:    float64Ptr(1.1),
+			Longitude:   float64Ptr(1.1),
 			MarkerColor: "FFFFFF",
 		},
 		{
 			ID:          "67d562e3d9f2d225ca4d9919",
 			Name:        "test2",
-			Latitude:    2.2,
-			Longitude:   2.2,
+			Latitude:    float64Ptr(2.2),
+			Longitude:   float64Ptr(2.2),
 			MarkerColor: "000000",
 		},
 		{
 			ID:          "67d562e3d9ddd225ca4d9919",
 			Name:        "test3",
-			Latitude:    3.3,
-			Longitude:   3.3,
+			Latitude:    float64Ptr(3.3),
+			Longitude:   float64Ptr(3.3),
 			MarkerColor: "000000",
 		},
 	},
@@ -97,12 +103,51 @@ var testUpdateLocationsReqForPartialContent = model.UpdateLocationsRequest{
 var testUpdateLocationsRes = model.UpdateLocationsResponse{
 	UpdatedIDs:   []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
 	FailedIDs:    []string{},
+	ConflictIDs:  []string{},
 	UpdatedCount: 2,
 }
 
+var testDeleteLocationReq = model.DeleteLocationRequest{
+	ID: "67d562e3d9f2d225ca4d9918",
+}
+
+var testDeleteLocationRes = model.DeleteLocationResponse{
+	ID: "67d562e3d9f2d225ca4d9918",
+}
+
+var testDeleteLocationsReq = model.DeleteLocationsRequest{
+	IDs: []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+}
+
+var testDeleteLocationsRes = model.DeleteLocationsResponse{
+	DeletedIDs:   []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+	FailedIDs:    []string{},
+	DeletedCount: 2,
+}
+
+var testArchiveLocationsReq = model.ArchiveLocationsRequest{
+	IDs: []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+}
+
+var testArchiveLocationsRes = model.ArchiveLocationsResponse{
+	ArchivedIDs:   []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+	FailedIDs:     []string{},
+	ArchivedCount: 2,
+}
+
+var testRestoreLocationsReq = model.RestoreLocationsRequest{
+	IDs: []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+}
+
+var testRestoreLocationsRes = model.RestoreLocationsResponse{
+	RestoredIDs:   []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+	FailedIDs:     []string{},
+	RestoredCount: 2,
+}
+
 var testGetRoutesReq = model.GetRoutesRequest{
-	Latitude:  1.1,
-	Longitude: 1.1,
+	Latitude:  float64Ptr(1.1),
+	Longitude: float64Ptr(1.1),
 }
 
 var testGetRoutesRes = model.GetRoutesResponse{
@@ -110,21 +155,83 @@ var testGetRoutesRes = model.GetRoutesResponse{
 		{
 			ID:          "67d562e3d9f2d225ca4d9918",
 			Name:        "test",
+			Latitude:    1.1,
+			Longitude:   1.1,
+			Distance:    0,
+			MarkerColor: "FFFFFF",
+		},
+	},
+}
+
+var testPlanRouteReq = model.PlanRouteRequest{
+	Start:         model.Coordinate{Latitude: float64Ptr(1.1), Longitude: float64Ptr(1.1)},
+	ReturnToStart: true,
+	LocationIDs:   []string{"67d562e3d9f2d225ca4d9918", "67d562e3d9f2d225ca4d9919"},
+}
+
+var testDistanceMatrixReq = model.DistanceMatrixRequest{
+	Origins: []model.MatrixPoint{
+		{Latitude: float64Ptr(1.1), Longitude: float64Ptr(1.1)},
+	},
+	Destinations: []model.MatrixPoint{
+		{ID: "67d562e3d9f2d225ca4d9918"},
+		{Latitude: float64Ptr(2.2), Longitude: float64Ptr(2.2)},
+	},
+}
+
+var testDistanceMatrixRes = model.DistanceMatrixResponse{
+	Origins: []model.MatrixPoint{
+		{Latitude: float64Ptr(1.1), Longitude: float64Ptr(1.1)},
+	},
+	Destinations: []model.MatrixPoint{
+		{ID: "67d562e3d9f2d225ca4d9918", Latitude: float64Ptr(1.1), Longitude: float64Ptr(1.1)},
+		{Latitude: float64Ptr(2.2), Longitude: float64Ptr(2.2)},
+	},
+	Distances: [][]float64{{0, 172.9}},
+}
+
+var testGetNearbyLocationsReq = model.GetNearbyLocationsRequest{
+	Latitude:  float64Ptr(1.1),
+	Longitude: float64Ptr(1.1),
+	RadiusKm:  10,
+	Page:      1,
+	Limit:     1,
+}
+
+var testGetNearbyLocationsRes = model.GetNearbyLocationsResponse{
+	Locations: []model.NearbyLocation{
+		{
+			ID:          "67d562e3d9f2d225ca4d9918",
+			Name:        "test",
+			Latitude:    1.1,
+			Longitude:   1.1,
 			Distance:    0,
 			MarkerColor: "FFFFFF",
 		},
 	},
 }
 
-var testGetRoutesDBResponse = model.GetAllLocationsDBResponse{Locations: []model.GetLocationResponse{
-	{
-		ID:          "67d562e3d9f2d225ca4d9918",
-		Name:        "test",
-		Latitude:    1.1,
-		Longitude:   1.1,
-		MarkerColor: "FFFFFF",
+var testGetLocationsInBoxReq = model.GetLocationsInBoxRequest{
+	MinLatitude:  float64Ptr(0),
+	MinLongitude: float64Ptr(170),
+	MaxLatitude:  float64Ptr(2.2),
+	MaxLongitude: float64Ptr(-170),
+	Page:         1,
+	Limit:        1,
+}
+
+var testGetLocationsInPolygonReq = model.GetLocationsInPolygonRequest{
+	GeoJSONPolygon: model.GeoJSONPolygon{
+		Type:        "Polygon",
+		Coordinates: [][][]float64{{{0, 0}, {2, 0}, {2, 2}, {0, 2}, {0, 0}}},
 	},
-}}
+	Page:  1,
+	Limit: 1,
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
 
 func TestService_CreateLocation(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -139,12 +246,32 @@ func TestService_CreateLocation(t *testing.T) {
 			Return(&testCreateLocationRes, nil).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
 		locationRes, _ := service.CreateLocation(&testCreateLocationReq)
 		assert.Equal(t, &testCreateLocationRes, locationRes)
 	})
 
+	t.Run("should clear cached routes", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+		routesCache := cache.NewMemoryCache(0)
+
+		mockRepository.
+			EXPECT().
+			CreateLocation(&testCreateLocationReq).
+			Return(&testCreateLocationRes, nil).
+			Times(1)
+
+		_ = routesCache.Set(cacheKey+":41:29:3:0:0", &model.GetRoutesResponse{}, cacheDuration)
+		_ = routesCache.Set("other", 1, cacheDuration)
+
+		service := NewService(mockRepository, routesCache)
+
+		_, err := service.CreateLocation(&testCreateLocationReq)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, routesCache.Len())
+	})
+
 	t.Run("return error", func(t *testing.T) {
 		mockRepository := NewMockStore(ctrl)
 
@@ -158,13 +285,123 @@ func TestService_CreateLocation(t *testing.T) {
 			Return(nil, &fiber.Error{Code: 500, Message: "Internal Server Error"}).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
 		_, err = service.CreateLocation(&testCreateLocationReq)
 		assert.Equal(t, expectedError, err)
 	})
 }
 
+func TestService_CreateLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should report invalid and failed items by their request index", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		invalid := model.CreateLocationRequest{Name: "x", Latitude: float64Ptr(1.1), Longitude: float64Ptr(1.1), MarkerColor: "FFFFFF"}
+		second := testCreateLocationReq
+		second.Name = "second"
+
+		mockRepository.
+			EXPECT().
+			CreateLocations(&model.CreateLocationsRequest{
+				Locations: []model.CreateLocationRequest{testCreateLocationReq, second},
+			}).
+			Return(&model.CreateLocationsResponse{
+				CreatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				Failed:       []model.CreateLocationError{{Index: 1, Error: "write failed"}},
+				CreatedCount: 1,
+			}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.CreateLocations(&model.CreateLocationsRequest{
+			Locations: []model.CreateLocationRequest{testCreateLocationReq, invalid, second},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"67d562e3d9f2d225ca4d9918"}, res.CreatedIDs)
+		assert.Equal(t, int64(1), res.CreatedCount)
+		assert.Len(t, res.Failed, 2)
+		assert.Equal(t, 1, res.Failed[0].Index)
+		assert.Equal(t, model.CreateLocationError{Index: 2, Error: "write failed"}, res.Failed[1])
+	})
+
+	t.Run("should not call the store when every item is invalid", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.CreateLocations(&model.CreateLocationsRequest{
+			Locations: []model.CreateLocationRequest{{Name: "x"}},
+		})
+		assert.Nil(t, err)
+		assert.Empty(t, res.CreatedIDs)
+		assert.Len(t, res.Failed, 1)
+		assert.Equal(t, 0, res.Failed[0].Index)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			CreateLocations(gomock.Any()).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.CreateLocations(&model.CreateLocationsRequest{
+			Locations: []model.CreateLocationRequest{testCreateLocationReq},
+		})
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestService_CreateLocationsFromGeoJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should create point features and report the rest by feature index", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			CreateLocations(&model.CreateLocationsRequest{
+				Locations: []model.CreateLocationRequest{testCreateLocationReq},
+			}).
+			Return(&model.CreateLocationsResponse{
+				CreatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				Failed:       []model.CreateLocationError{},
+				CreatedCount: 1,
+			}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.CreateLocationsFromGeoJSON(&model.ImportGeoJSONRequest{
+			Type: "FeatureCollection",
+			Features: []model.GeoJSONFeature{
+				{
+					Type:       "Feature",
+					Geometry:   model.GeoJSONGeometry{Type: "LineString"},
+					Properties: map[string]interface{}{},
+				},
+				{
+					Type:       "Feature",
+					Geometry:   model.GeoJSONGeometry{Type: "Point", Coordinates: []interface{}{1.1, 1.1}},
+					Properties: map[string]interface{}{"name": "test", "marker_color": "FFFFFF"},
+				},
+			},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"67d562e3d9f2d225ca4d9918"}, res.CreatedIDs)
+		assert.Equal(t, []model.CreateLocationError{{Index: 0, Error: errFeatureNotPoint.Error()}}, res.Failed)
+	})
+}
+
 func TestService_GetLocation(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -178,7 +415,7 @@ func TestService_GetLocation(t *testing.T) {
 			Return(&testGetLocationRes, nil).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
 		locationRes, _ := service.GetLocation(&testGetLocationReq)
 		assert.Equal(t, &testGetLocationRes, locationRes)
@@ -197,11 +434,155 @@ func TestService_GetLocation(t *testing.T) {
 			Return(nil, &fiber.Error{Code: 500, Message: "Internal Server Error"}).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
 		_, err = service.GetLocation(&testGetLocationReq)
 		assert.Equal(t, expectedError, err)
 	})
+
+	t.Run("should read the location from the history with as_of", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		asOf := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+
+		mockRepository.
+			EXPECT().
+			GetLocationAsOf(testGetLocationReq.ID, asOf).
+			Return(&testGetLocationRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationRes, err := service.GetLocation(&model.GetLocationRequest{ID: testGetLocationReq.ID, AsOf: "2025-03-15T12:00:00Z"})
+		assert.Nil(t, err)
+		assert.Equal(t, &testGetLocationRes, locationRes)
+	})
+
+	t.Run("should serve the location from the cache until it is updated", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+		otherReq := model.GetLocationRequest{ID: "67d562e3d9f2d225ca4d9920"}
+		updateRes := model.UpdateLocationsResponse{UpdatedIDs: []string{testGetLocationReq.ID}, UpdatedCount: 1}
+
+		mockRepository.
+			EXPECT().
+			GetLocation(&testGetLocationReq).
+			Return(&testGetLocationRes, nil).
+			Times(2)
+		mockRepository.
+			EXPECT().
+			GetLocation(&otherReq).
+			Return(&testGetLocationRes, nil).
+			Times(1)
+		mockRepository.
+			EXPECT().
+			UpdateLocations(&testUpdateLocationsReq).
+			Return(&updateRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		for i := 0; i < 2; i++ {
+			locationRes, err := service.GetLocation(&testGetLocationReq)
+			assert.Nil(t, err)
+			assert.Equal(t, &testGetLocationRes, locationRes)
+
+			_, err = service.GetLocation(&otherReq)
+			assert.Nil(t, err)
+		}
+
+		_, err := service.UpdateLocations(&testUpdateLocationsReq)
+		assert.Nil(t, err)
+
+		_, err = service.GetLocation(&testGetLocationReq)
+		assert.Nil(t, err)
+		_, err = service.GetLocation(&otherReq)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should not cache a version read before an update", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+		updateRes := model.UpdateLocationsResponse{UpdatedIDs: []string{testGetLocationReq.ID}, UpdatedCount: 1}
+		updated := testGetLocationRes
+		updated.Version = 4
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		gomock.InOrder(
+			mockRepository.
+				EXPECT().
+				GetLocation(&testGetLocationReq).
+				DoAndReturn(func(*model.GetLocationRequest) (*model.GetLocationResponse, error) {
+					close(started)
+					<-release
+
+					return &testGetLocationRes, nil
+				}).
+				Times(1),
+			mockRepository.
+				EXPECT().
+				GetLocation(&testGetLocationReq).
+				Return(&updated, nil).
+				Times(1),
+		)
+		mockRepository.
+			EXPECT().
+			UpdateLocations(&testUpdateLocationsReq).
+			Return(&updateRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		done := make(chan *model.GetLocationResponse)
+
+		go func() {
+			locationRes, err := service.GetLocation(&testGetLocationReq)
+			assert.Nil(t, err)
+			done <- locationRes
+		}()
+
+		<-started
+
+		_, err := service.UpdateLocations(&testUpdateLocationsReq)
+		assert.Nil(t, err)
+
+		close(release)
+		assert.Equal(t, int64(3), (<-done).Version)
+
+		locationRes, err := service.GetLocation(&testGetLocationReq)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(4), locationRes.Version)
+	})
+}
+
+func TestService_GetLocationHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should get location history properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		req := &model.GetLocationHistoryRequest{ID: testGetLocationReq.ID}
+		expected := &model.GetLocationHistoryResponse{History: []model.LocationHistoryEntry{{
+			LocationID: testGetLocationReq.ID,
+			Action:     model.HistoryActionDelete,
+			Version:    2,
+			Actor:      "jane",
+			Before:     &model.LocationState{Name: "test", Latitude: 1.1, Longitude: 1.1, MarkerColor: "FFFFFF"},
+		}}}
+
+		mockRepository.
+			EXPECT().
+			GetLocationHistory(req).
+			Return(expected, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.GetLocationHistory(req)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, res)
+	})
 }
 
 func TestService_GetLocations(t *testing.T) {
@@ -217,7 +598,7 @@ func TestService_GetLocations(t *testing.T) {
 			Return(&testGetLocationsRes, nil).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
 		locationsRes, _ := service.GetLocations(&testGetLocationsReq)
 		assert.Equal(t, &testGetLocationsRes, locationsRes)
@@ -236,86 +617,956 @@ func TestService_GetLocations(t *testing.T) {
 			Return(nil, &fiber.Error{Code: 500, Message: "Internal Server Error"}).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
 		_, err = service.GetLocations(&testGetLocationsReq)
 		assert.Equal(t, expectedError, err)
 	})
-}
-
-func TestService_UpdateLocations(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
 
-	t.Run("should update locations properly", func(t *testing.T) {
+	t.Run("should serve equal queries from the cache until a location changes", func(t *testing.T) {
 		mockRepository := NewMockStore(ctrl)
+		req := model.GetLocationsRequest{Fields: "name,id", CreatedAfter: "2025-03-15T15:00:00+03:00"}
+		sameReq := model.GetLocationsRequest{Page: 1, Limit: 10, Fields: " id , name", CreatedAfter: "2025-03-15T12:00:00Z"}
 
 		mockRepository.
 			EXPECT().
-			UpdateLocations(&testUpdateLocationsReq).
-			Return(&testUpdateLocationsRes, nil).
+			GetLocations(gomock.Any()).
+			Return(&testGetLocationsRes, nil).
+			Times(2)
+		mockRepository.
+			EXPECT().
+			CreateLocation(&testCreateLocationReq).
+			Return(&testCreateLocationRes, nil).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
-		locationsRes, err := service.UpdateLocations(&testUpdateLocationsReq)
+		locationsRes, err := service.GetLocations(&req)
+		assert.Nil(t, err)
+		assert.Equal(t, &testGetLocationsRes, locationsRes)
+
+		locationsRes, err = service.GetLocations(&sameReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testGetLocationsRes, locationsRes)
+
+		_, err = service.CreateLocation(&testCreateLocationReq)
+		assert.Nil(t, err)
+
+		_, err = service.GetLocations(&req)
 		assert.Nil(t, err)
-		assert.Equal(t, &testUpdateLocationsRes, locationsRes)
 	})
+}
 
-	t.Run("should return error when update fails", func(t *testing.T) {
+func TestLocationsQueryKey(t *testing.T) {
+	t.Run("should fill in defaults and order the parameters", func(t *testing.T) {
+		key := locationsQueryKey(&model.GetLocationsRequest{
+			Sort:         "-name",
+			MarkerColor:  "FFFFFF",
+			Fields:       "name, id",
+			UpdatedAfter: "2025-03-15T15:00:00+03:00",
+		})
+
+		assert.Equal(t, "fields=id%2Cname&limit=10&marker_color=FFFFFF&page=1&sort=-name&updated_after=2025-03-15T12%3A00%3A00Z", key)
+	})
+
+	t.Run("should tell pages and cursors apart", func(t *testing.T) {
+		assert.NotEqual(t,
+			locationsQueryKey(&model.GetLocationsRequest{Page: 2}),
+			locationsQueryKey(&model.GetLocationsRequest{Page: 3}))
+		assert.Equal(t, "cursor=abc&limit=5&total=true", locationsQueryKey(&model.GetLocationsRequest{Cursor: "abc", Limit: 5, Total: true}))
+	})
+}
+
+func TestService_ImportLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	data := "name,latitude,longitude,marker_color,notes\n" +
+		"test,1.1,1.1,FFFFFF,first\n" +
+		"x,1.1,1.1,FFFFFF,name too short\n" +
+		"test,abc,1.1,FFFFFF,bad latitude\n"
+
+	t.Run("should only validate in a dry run", func(t *testing.T) {
 		mockRepository := NewMockStore(ctrl)
 
-		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.ImportLocations(&model.ImportLocationsRequest{DryRun: true}, strings.NewReader(data))
+		assert.Nil(t, err)
+		assert.True(t, res.DryRun)
+		assert.Equal(t, 1, res.ValidCount)
+		assert.Empty(t, res.CreatedIDs)
+		assert.Equal(t, []string{"notes"}, res.IgnoredColumns)
+		assert.Len(t, res.Failed, 2)
+		assert.Equal(t, 3, res.Failed[0].Line)
+		assert.Equal(t, 4, res.Failed[1].Line)
+	})
+
+	t.Run("should create valid rows and report the rest by line", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
 
 		mockRepository.
 			EXPECT().
-			UpdateLocations(&testUpdateLocationsReq).
-			Return(nil, expectedError).
+			CreateLocations(&model.CreateLocationsRequest{
+				Locations: []model.CreateLocationRequest{testCreateLocationReq},
+			}).
+			Return(&model.CreateLocationsResponse{
+				CreatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+				Failed:       []model.CreateLocationError{},
+				CreatedCount: 1,
+			}, nil).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
-		_, err := service.UpdateLocations(&testUpdateLocationsReq)
-		assert.Equal(t, expectedError, err)
+		res, err := service.ImportLocations(&model.ImportLocationsRequest{}, strings.NewReader(data))
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"67d562e3d9f2d225ca4d9918"}, res.CreatedIDs)
+		assert.Equal(t, int64(1), res.CreatedCount)
+		assert.Len(t, res.Failed, 2)
+		assert.Equal(t, 3, res.Failed[0].Line)
+		assert.Equal(t, 4, res.Failed[1].Line)
+	})
+
+	t.Run("return invalid csv error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.ImportLocations(&model.ImportLocationsRequest{}, strings.NewReader("name\ntest\n"))
+		assert.ErrorIs(t, err, model.ErrInvalidCSV)
 	})
 }
 
-func TestService_GetRoutes(t *testing.T) {
+func TestService_ExportLocations(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	t.Run("should get routes properly", func(t *testing.T) {
+	t.Run("should write every streamed location", func(t *testing.T) {
 		mockRepository := NewMockStore(ctrl)
 
 		mockRepository.
 			EXPECT().
-			GetRoutes().
-			Return(&testGetRoutesDBResponse, nil).
+			StreamLocations(gomock.Any()).
+			DoAndReturn(func(fn func(location *model.GetLocationResponse) error) error {
+				return fn(&testGetLocationRes)
+			}).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
-		routesRes, _ := service.GetRoutes(&testGetRoutesReq)
-		assert.Equal(t, &testGetRoutesRes, routesRes)
+		var buf bytes.Buffer
+
+		err := service.ExportLocations(&model.ExportLocationsRequest{Format: "csv"}, &buf)
+		assert.Nil(t, err)
+		assert.Contains(t, buf.String(), testGetLocationRes.ID)
 	})
 
 	t.Run("return error", func(t *testing.T) {
 		mockRepository := NewMockStore(ctrl)
 
-		var err error
-
-		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
-
 		mockRepository.
 			EXPECT().
-			GetRoutes().
-			Return(nil, &fiber.Error{Code: 500, Message: "Internal Server Error"}).
+			StreamLocations(gomock.Any()).
+			Return(assert.AnError).
 			Times(1)
 
-		service := NewService(mockRepository)
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
 
-		_, err = service.GetRoutes(&testGetRoutesReq)
+		err := service.ExportLocations(&model.ExportLocationsRequest{}, &bytes.Buffer{})
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestService_UpdateLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should update locations properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			UpdateLocations(&testUpdateLocationsReq).
+			Return(&testUpdateLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationsRes, err := service.UpdateLocations(&testUpdateLocationsReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testUpdateLocationsRes, locationsRes)
+	})
+
+	t.Run("should return error when update fails", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			UpdateLocations(&testUpdateLocationsReq).
+			Return(nil, expectedError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.UpdateLocations(&testUpdateLocationsReq)
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestService_PatchLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	req := &model.PatchLocationsRequest{
+		Locations: []model.LocationPatch{
+			{ID: "67d562e3d9f2d225ca4d9918", Fields: map[string]json.RawMessage{"marker_color": json.RawMessage("null")}},
+		},
+	}
+
+	t.Run("should patch locations properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expected := &model.UpdateLocationsResponse{
+			UpdatedIDs:   []string{"67d562e3d9f2d225ca4d9918"},
+			FailedIDs:    []string{},
+			UpdatedCount: 1,
+		}
+
+		mockRepository.
+			EXPECT().
+			PatchLocations(req).
+			Return(expected, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.PatchLocations(req)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, res)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			PatchLocations(req).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.PatchLocations(req)
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestService_DeleteLocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should delete location properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			DeleteLocation(&testDeleteLocationReq).
+			Return(&testDeleteLocationRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationRes, err := service.DeleteLocation(&testDeleteLocationReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testDeleteLocationRes, locationRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			DeleteLocation(&testDeleteLocationReq).
+			Return(nil, expectedError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.DeleteLocation(&testDeleteLocationReq)
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestService_DeleteLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should delete locations properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			DeleteLocations(&testDeleteLocationsReq).
+			Return(&testDeleteLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationsRes, err := service.DeleteLocations(&testDeleteLocationsReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testDeleteLocationsRes, locationsRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			DeleteLocations(&testDeleteLocationsReq).
+			Return(nil, expectedError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.DeleteLocations(&testDeleteLocationsReq)
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestService_ArchiveLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should archive locations properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			ArchiveLocations(&testArchiveLocationsReq).
+			Return(&testArchiveLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationsRes, err := service.ArchiveLocations(&testArchiveLocationsReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testArchiveLocationsRes, locationsRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			ArchiveLocations(&testArchiveLocationsReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.ArchiveLocations(&testArchiveLocationsReq)
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestService_RevertLocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	version := int64(2)
+	req := &model.RevertLocationRequest{ID: testGetLocationReq.ID, Version: &version}
+
+	t.Run("should revert location properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			RevertLocation(req).
+			Return(&testGetLocationRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationRes, err := service.RevertLocation(req)
+		assert.Nil(t, err)
+		assert.Equal(t, &testGetLocationRes, locationRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			RevertLocation(req).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.RevertLocation(req)
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestService_RestoreLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should restore locations properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			RestoreLocations(&testRestoreLocationsReq).
+			Return(&testRestoreLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationsRes, err := service.RestoreLocations(&testRestoreLocationsReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testRestoreLocationsRes, locationsRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			RestoreLocations(&testRestoreLocationsReq).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.RestoreLocations(&testRestoreLocationsReq)
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestService_PurgeArchivedLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should purge locations archived before the retention period", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		retention := 24 * time.Hour
+		before := time.Now().Add(-retention)
+
+		mockRepository.
+			EXPECT().
+			PurgeArchivedLocations(gomock.Any()).
+			DoAndReturn(func(archivedBefore time.Time) (int64, error) {
+				assert.False(t, archivedBefore.Before(before))
+				assert.True(t, archivedBefore.Before(time.Now().Add(-retention+time.Minute)))
+
+				return 3, nil
+			}).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		purged, err := service.PurgeArchivedLocations(retention)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), purged)
+	})
+}
+
+func TestService_GetRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should get routes properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			GetRoutes(&testGetRoutesReq).
+			Return(&testGetRoutesRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		routesRes, _ := service.GetRoutes(&testGetRoutesReq)
+		assert.Equal(t, &testGetRoutesRes, routesRes)
+	})
+
+	t.Run("should treat a zero coordinate as a real position", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		req := &model.GetRoutesRequest{Latitude: float64Ptr(0), Longitude: float64Ptr(0)}
+
+		mockRepository.
+			EXPECT().
+			GetRoutes(req).
+			Return(&testGetRoutesRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		routesRes, err := service.GetRoutes(req)
+		assert.Nil(t, err)
+		assert.Equal(t, &testGetRoutesRes, routesRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		var err error
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			GetRoutes(&testGetRoutesReq).
+			Return(nil, &fiber.Error{Code: 500, Message: "Internal Server Error"}).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err = service.GetRoutes(&testGetRoutesReq)
+		assert.Equal(t, expectedError, err)
+	})
+
+	t.Run("should order routes as a tour in tour mode", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		req := &model.GetRoutesRequest{Latitude: float64Ptr(1), Longitude: float64Ptr(1), Mode: model.RouteModeTour}
+		limited := *req
+		limited.Limit = defaultTourLimit
+
+		mockRepository.
+			EXPECT().
+			GetRoutes(&limited).
+			Return(&model.GetRoutesResponse{Routes: []model.Route{
+				{ID: "a", Latitude: 1, Longitude: 2, Distance: 111},
+				{ID: "b", Latitude: 1, Longitude: 0, Distance: 111},
+				{ID: "c", Latitude: 1, Longitude: 3, Distance: 222},
+				{ID: "d", Latitude: 1, Longitude: -0.5, Distance: 167},
+			}}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		routesRes, err := service.GetRoutes(req)
+		assert.Nil(t, err)
+
+		ids := make([]string, 0, len(routesRes.Routes))
+		for _, route := range routesRes.Routes {
+			ids = append(ids, route.ID)
+		}
+
+		assert.Equal(t, []string{"b", "d", "a", "c"}, ids)
+
+		var legs float64
+		for _, route := range routesRes.Routes {
+			legs += route.LegDistance
+			assert.InDelta(t, legs, route.CumulativeDistance, 1e-9)
+		}
+
+		assert.InDelta(t, legs, routesRes.TotalDistance, 1e-9)
+		assert.InDelta(t, 555.9, routesRes.TotalDistance, 1.0)
+	})
+}
+
+func TestService_PlanRoute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should plan a round trip and report missing locations", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		req := &model.PlanRouteRequest{
+			Start:         model.Coordinate{Latitude: float64Ptr(1), Longitude: float64Ptr(1)},
+			ReturnToStart: true,
+			LocationIDs:   []string{"a", "b", "missing", "a"},
+		}
+
+		mockRepository.
+			EXPECT().
+			GetLocationsByIDs([]string{"a", "b", "missing"}).
+			Return(&model.GetLocationsResponse{Locations: []model.GetLocationResponse{
+				{ID: "a", Latitude: 1, Longitude: 2},
+				{ID: "b", Latitude: 1, Longitude: 3},
+			}}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		routesRes, err := service.PlanRoute(req)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"missing"}, routesRes.MissingIDs)
+		assert.Equal(t, &req.Start, routesRes.Start)
+		assert.Equal(t, &req.Start, routesRes.End)
+
+		if assert.Len(t, routesRes.Routes, 2) {
+			assert.Equal(t, "a", routesRes.Routes[0].ID)
+			assert.Equal(t, "b", routesRes.Routes[1].ID)
+			assert.InDelta(t, routesRes.Routes[1].Distance, routesRes.FinalLegDistance, 1e-9)
+			assert.InDelta(t, routesRes.Routes[1].CumulativeDistance+routesRes.FinalLegDistance, routesRes.TotalDistance, 1e-9)
+		}
+	})
+
+	t.Run("should finish at the given end", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		req := &model.PlanRouteRequest{
+			Start:       model.Coordinate{Latitude: float64Ptr(1), Longitude: float64Ptr(1)},
+			End:         &model.Coordinate{Latitude: float64Ptr(1), Longitude: float64Ptr(5)},
+			LocationIDs: []string{"a", "b"},
+		}
+
+		mockRepository.
+			EXPECT().
+			GetLocationsByIDs([]string{"a", "b"}).
+			Return(&model.GetLocationsResponse{Locations: []model.GetLocationResponse{
+				{ID: "a", Latitude: 1, Longitude: 4},
+				{ID: "b", Latitude: 1, Longitude: 0},
+			}}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		routesRes, err := service.PlanRoute(req)
+		assert.Nil(t, err)
+		assert.Empty(t, routesRes.MissingIDs)
+
+		if assert.Len(t, routesRes.Routes, 2) {
+			assert.Equal(t, "b", routesRes.Routes[0].ID)
+			assert.Equal(t, "a", routesRes.Routes[1].ID)
+		}
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			GetLocationsByIDs(testPlanRouteReq.LocationIDs).
+			Return(nil, expectedError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.PlanRoute(&testPlanRouteReq)
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestService_GetDistanceMatrix(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should get distance matrix properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			GetLocationsByIDs([]string{"67d562e3d9f2d225ca4d9918"}).
+			Return(&testGetLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		matrixRes, err := service.GetDistanceMatrix(&testDistanceMatrixReq)
+		assert.Nil(t, err)
+		assert.Equal(t, testDistanceMatrixRes.Origins, matrixRes.Origins)
+		assert.Equal(t, testDistanceMatrixRes.Destinations, matrixRes.Destinations)
+
+		if assert.Len(t, matrixRes.Distances, 1) && assert.Len(t, matrixRes.Distances[0], 2) {
+			assert.InDelta(t, 0, matrixRes.Distances[0][0], 1e-9)
+			assert.InDelta(t, 172.9, matrixRes.Distances[0][1], 1.0)
+		}
+	})
+
+	t.Run("should resolve distance matrix without distances", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			GetLocationsByIDs([]string{"67d562e3d9f2d225ca4d9918"}).
+			Return(&testGetLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		matrixRes, err := service.ResolveDistanceMatrix(&testDistanceMatrixReq)
+		assert.Nil(t, err)
+		assert.Equal(t, testDistanceMatrixRes.Destinations, matrixRes.Destinations)
+		assert.Nil(t, matrixRes.Distances)
+	})
+
+	t.Run("should not query store without ids", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		matrixRes, err := service.GetDistanceMatrix(&model.DistanceMatrixRequest{
+			Origins:      []model.MatrixPoint{{Latitude: float64Ptr(0), Longitude: float64Ptr(0)}},
+			Destinations: []model.MatrixPoint{{Latitude: float64Ptr(0), Longitude: float64Ptr(1)}},
+		})
+		assert.Nil(t, err)
+		assert.InDelta(t, 111.2, matrixRes.Distances[0][0], 1.0)
+	})
+
+	t.Run("should report missing locations", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			GetLocationsByIDs([]string{"67d562e3d9f2d225ca4d9918"}).
+			Return(&model.GetLocationsResponse{}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		matrixRes, err := service.GetDistanceMatrix(&testDistanceMatrixReq)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"67d562e3d9f2d225ca4d9918"}, matrixRes.MissingIDs)
+		assert.Nil(t, matrixRes.Distances)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			GetLocationsByIDs([]string{"67d562e3d9f2d225ca4d9918"}).
+			Return(nil, expectedError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.GetDistanceMatrix(&testDistanceMatrixReq)
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestService_GetNearbyLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should get nearby locations properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			GetNearbyLocations(&testGetNearbyLocationsReq).
+			Return(&testGetNearbyLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationsRes, err := service.GetNearbyLocations(&testGetNearbyLocationsReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testGetNearbyLocationsRes, locationsRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			GetNearbyLocations(&testGetNearbyLocationsReq).
+			Return(nil, expectedError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.GetNearbyLocations(&testGetNearbyLocationsReq)
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestService_SearchLocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	galata := model.LocationSearchResult{ID: "1", Name: "Galata Tower", Latitude: 41.0256, Longitude: 28.9741}
+	maiden := model.LocationSearchResult{ID: "2", Name: "Maiden's Tower", Latitude: 41.0211, Longitude: 29.0041}
+	moda := model.LocationSearchResult{ID: "3", Name: "Moda Pier", Latitude: 40.9816, Longitude: 29.0250}
+
+	t.Run("should rank text matches without the fuzzy fallback when they fill the page", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		first, second := galata, maiden
+		first.Score, second.Score = 1.5, 0.75
+
+		mockRepository.
+			EXPECT().
+			TextSearchLocations("tower", int64(searchCandidateLimit)).
+			Return([]model.LocationSearchResult{first, second}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.SearchLocations(&model.SearchLocationsRequest{Query: " tower ", Limit: 1})
+		assert.Nil(t, err)
+		assert.Len(t, res.Locations, 1)
+		assert.Equal(t, "1", res.Locations[0].ID)
+		assert.Equal(t, 1.0, res.Locations[0].Score)
+	})
+
+	t.Run("should fall back to trigrams for typos and drop weak candidates", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			TextSearchLocations("galta towr", int64(searchCandidateLimit)).
+			Return([]model.LocationSearchResult{}, nil).
+			Times(1)
+
+		mockRepository.
+			EXPECT().
+			FuzzySearchLocations("galta towr", int64(searchCandidateLimit)).
+			Return([]model.LocationSearchResult{moda, maiden, galata}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.SearchLocations(&model.SearchLocationsRequest{Query: "galta towr"})
+		assert.Nil(t, err)
+		assert.NotEmpty(t, res.Locations)
+		assert.Equal(t, "1", res.Locations[0].ID)
+
+		for _, location := range res.Locations {
+			assert.NotEqual(t, "3", location.ID)
+		}
+	})
+
+	t.Run("should rank a prefix high and move closer locations up", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		galataPier := model.LocationSearchResult{ID: "4", Name: "Galata Pier", Latitude: 41.0220, Longitude: 28.9750}
+
+		mockRepository.
+			EXPECT().
+			TextSearchLocations("gal", int64(searchCandidateLimit)).
+			Return([]model.LocationSearchResult{}, nil).
+			Times(1)
+
+		mockRepository.
+			EXPECT().
+			FuzzySearchLocations("gal", int64(searchCandidateLimit)).
+			Return([]model.LocationSearchResult{galata, galataPier}, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		res, err := service.SearchLocations(&model.SearchLocationsRequest{
+			Query:     "gal",
+			Latitude:  float64Ptr(41.0220),
+			Longitude: float64Ptr(28.9750),
+		})
+		assert.Nil(t, err)
+		assert.Len(t, res.Locations, 2)
+		assert.Equal(t, "4", res.Locations[0].ID)
+		assert.Equal(t, 0.0, *res.Locations[0].Distance)
+		assert.Greater(t, *res.Locations[1].Distance, 0.0)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			TextSearchLocations("tower", int64(searchCandidateLimit)).
+			Return(nil, assert.AnError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.SearchLocations(&model.SearchLocationsRequest{Query: "tower"})
+		assert.Equal(t, assert.AnError, err)
+	})
+}
+
+func TestService_GetLocationsInBox(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should get locations in box properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			GetLocationsInBox(&testGetLocationsInBoxReq).
+			Return(&testGetLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationsRes, err := service.GetLocationsInBox(&testGetLocationsInBoxReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testGetLocationsRes, locationsRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			GetLocationsInBox(&testGetLocationsInBoxReq).
+			Return(nil, expectedError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.GetLocationsInBox(&testGetLocationsInBoxReq)
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestService_GetLocationsInPolygon(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Run("should get locations in polygon properly", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		mockRepository.
+			EXPECT().
+			GetLocationsInPolygon(&testGetLocationsInPolygonReq).
+			Return(&testGetLocationsRes, nil).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		locationsRes, err := service.GetLocationsInPolygon(&testGetLocationsInPolygonReq)
+		assert.Nil(t, err)
+		assert.Equal(t, &testGetLocationsRes, locationsRes)
+	})
+
+	t.Run("return error", func(t *testing.T) {
+		mockRepository := NewMockStore(ctrl)
+
+		expectedError := fiber.NewError(fiber.StatusInternalServerError, "Internal Server Error")
+
+		mockRepository.
+			EXPECT().
+			GetLocationsInPolygon(&testGetLocationsInPolygonReq).
+			Return(nil, expectedError).
+			Times(1)
+
+		service := NewService(mockRepository, cache.NewMemoryCache(0))
+
+		_, err := service.GetLocationsInPolygon(&testGetLocationsInPolygonReq)
 		assert.Equal(t, expectedError, err)
 	})
 }
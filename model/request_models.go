@@ -1,52 +1,526 @@
 package model
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
 )
 
 var validate = validator.New()
 
+const (
+	RouteModeDistance = "distance"
+	RouteModeTour     = "tour"
+)
+
 type CreateLocationRequest struct {
-	Name        string  `json:"name" bson:"name" validate:"required,min=3"`
-	Latitude    float64 `json:"latitude" bson:"latitude" validate:"required"`
-	Longitude   float64 `json:"longitude" bson:"longitude" validate:"required"`
-	MarkerColor string  `json:"marker_color" bson:"marker_color" validate:"required,len=6,hexadecimal"`
+	Name        string   `json:"name" bson:"name" validate:"required,min=3"`
+	Latitude    *float64 `json:"latitude" bson:"latitude" validate:"required,min=-90,max=90"`
+	Longitude   *float64 `json:"longitude" bson:"longitude" validate:"required,min=-180,max=180"`
+	MarkerColor string   `json:"marker_color" bson:"marker_color" validate:"required,len=6,hexadecimal"`
+	Actor       string   `json:"-" bson:"-"`
+}
+
+type CreateLocationsRequest struct {
+	Locations []CreateLocationRequest `json:"locations" bson:"locations" validate:"required,min=1,max=10000"`
+	Actor     string                  `json:"-" bson:"-"`
+}
+
+type ImportGeoJSONRequest struct {
+	Type     string           `json:"type" validate:"required,eq=FeatureCollection"`
+	Features []GeoJSONFeature `json:"features" validate:"required,min=1,max=10000"`
+	Actor    string           `json:"-"`
+}
+
+type ImportLocationsRequest struct {
+	DryRun bool   `query:"dry_run"`
+	Actor  string `query:"-"`
+}
+
+type ExportLocationsRequest struct {
+	Format string `query:"format"`
 }
 
 type GetLocationRequest struct {
-	ID string `query:"id" json:"id" bson:"_id" validate:"required"`
+	ID   string `query:"id" json:"id" bson:"_id" validate:"required"`
+	AsOf string `query:"as_of" json:"as_of" bson:"-" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type GetLocationHistoryRequest struct {
+	ID    string `query:"id" json:"id" bson:"_id" validate:"required"`
+	Page  int    `query:"page" json:"page" bson:"page" validate:"omitempty,min=1"`
+	Limit int    `query:"limit" json:"limit" bson:"limit" validate:"omitempty,min=1,max=1000"`
 }
 
+// GetLocationsRequest pages through locations with the opaque cursor returned
+// as next_cursor. Page is still accepted for older clients but cannot be
+// combined with a cursor. Sort takes one of the sortable fields, prefixed with
+// "-" for descending order, and Fields a comma separated list of the fields to
+// return.
 type GetLocationsRequest struct {
-	Page  int `query:"page" json:"page" bson:"page" validate:"required"`
-	Limit int `query:"limit" json:"limit" bson:"limit" validate:"required"`
+	Page          int    `query:"page" json:"page" bson:"page" validate:"omitempty,min=1,excluded_with=Cursor"`
+	Limit         int    `query:"limit" json:"limit" bson:"limit" validate:"omitempty,min=1,max=1000"`
+	Cursor        string `query:"cursor" json:"cursor" bson:"-"`
+	Total         bool   `query:"total" json:"total" bson:"-"`
+	NamePrefix    string `query:"name_prefix" json:"name_prefix" bson:"-" validate:"omitempty,max=100"`
+	NameContains  string `query:"name_contains" json:"name_contains" bson:"-" validate:"omitempty,max=100"`
+	MarkerColor   string `query:"marker_color" json:"marker_color" bson:"-" validate:"omitempty,len=6,hexadecimal"`
+	CreatedAfter  string `query:"created_after" json:"created_after" bson:"-" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedBefore string `query:"created_before" json:"created_before" bson:"-" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedAfter  string `query:"updated_after" json:"updated_after" bson:"-" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedBefore string `query:"updated_before" json:"updated_before" bson:"-" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Sort          string `query:"sort" json:"sort" bson:"-"`
+	Fields        string `query:"fields" json:"fields" bson:"-"`
+}
+
+// LocationSortFields are the fields GET /locations can be sorted by.
+var LocationSortFields = map[string]bool{
+	"name":         true,
+	"marker_color": true,
+	"latitude":     true,
+	"longitude":    true,
+	"created_at":   true,
+	"updated_at":   true,
+}
+
+// LocationFields are the fields GET /locations can be asked to return.
+var LocationFields = map[string]bool{
+	"id":           true,
+	"name":         true,
+	"latitude":     true,
+	"longitude":    true,
+	"marker_color": true,
+	"version":      true,
+	"created_at":   true,
+	"updated_at":   true,
+}
+
+// RevertLocationRequest names the snapshot to roll a location back to, either by
+// version or as it was at a point in time.
+type RevertLocationRequest struct {
+	ID      string `json:"id" validate:"required"`
+	Version *int64 `json:"version" validate:"omitempty,min=1"`
+	AsOf    string `json:"as_of" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Actor   string `json:"-"`
 }
 
 type UpdateLocation struct {
-	ID          string  `json:"id" bson:"_id" validate:"required"`
-	Name        string  `json:"name" bson:"name" validate:"omitempty,min=3"`
-	Latitude    float64 `json:"latitude" bson:"latitude" validate:"omitempty"`
-	Longitude   float64 `json:"longitude" bson:"longitude" validate:"omitempty"`
-	MarkerColor string  `json:"marker_color" bson:"marker_color" validate:"omitempty,len=6,hexadecimal"`
+	ID              string   `json:"id" bson:"_id" validate:"required"`
+	Name            string   `json:"name" bson:"name" validate:"omitempty,min=3"`
+	Latitude        *float64 `json:"latitude" bson:"latitude" validate:"omitempty,min=-90,max=90"`
+	Longitude       *float64 `json:"longitude" bson:"longitude" validate:"omitempty,min=-180,max=180"`
+	MarkerColor     string   `json:"marker_color" bson:"marker_color" validate:"omitempty,len=6,hexadecimal"`
+	ExpectedVersion *int64   `json:"expected_version" bson:"-" validate:"omitempty,min=1"`
 }
 
 type UpdateLocationsRequest struct {
 	Locations []UpdateLocation `json:"locations" bson:"locations" validate:"required,dive"`
+	Actor     string           `json:"-" bson:"-"`
+}
+
+// LocationPatch is a JSON Merge Patch (RFC 7396) for one location: the id picks
+// the location, expected_version optionally guards it, and every other member
+// is a change, where null removes the field.
+type LocationPatch struct {
+	ID              string
+	ExpectedVersion *int64
+	Fields          map[string]json.RawMessage
+}
+
+type PatchLocationsRequest struct {
+	Locations []LocationPatch `json:"locations" validate:"required,min=1,max=1000,dive"`
+	Actor     string          `json:"-"`
+}
+
+type patchField struct {
+	number    bool
+	tag       string
+	removable bool
+}
+
+// locationPatchFields lists the fields a patch may touch with the validation
+// their new values must pass.
+var locationPatchFields = map[string]patchField{
+	"name":         {tag: "min=3"},
+	"latitude":     {number: true, tag: "min=-90,max=90"},
+	"longitude":    {number: true, tag: "min=-180,max=180"},
+	"marker_color": {tag: "len=6,hexadecimal", removable: true},
+}
+
+type DeleteLocationRequest struct {
+	ID    string `query:"id" json:"id" bson:"_id" validate:"required"`
+	Actor string `query:"-" json:"-" bson:"-"`
+}
+
+type DeleteLocationsRequest struct {
+	IDs   []string `json:"ids" bson:"ids" validate:"required,min=1,max=1000,dive,required"`
+	Actor string   `json:"-" bson:"-"`
+}
+
+type ArchiveLocationsRequest struct {
+	IDs   []string `json:"ids" bson:"ids" validate:"required,min=1,max=1000,dive,required"`
+	Actor string   `json:"-" bson:"-"`
+}
+
+type RestoreLocationsRequest struct {
+	IDs   []string `json:"ids" bson:"ids" validate:"required,min=1,max=1000,dive,required"`
+	Actor string   `json:"-" bson:"-"`
+}
+
+type GetArchivedLocationsRequest struct {
+	Page  int `query:"page" json:"page" bson:"page" validate:"omitempty,min=1"`
+	Limit int `query:"limit" json:"limit" bson:"limit" validate:"omitempty,min=1,max=1000"`
 }
 
 type GetRoutesRequest struct {
-	Latitude  float64 `query:"latitude" json:"latitude" bson:"latitude" validate:"required"`
-	Longitude float64 `query:"longitude" json:"longitude" bson:"longitude" validate:"required"`
+	Latitude    *float64 `query:"latitude" json:"latitude" bson:"latitude" validate:"required,min=-90,max=90"`
+	Longitude   *float64 `query:"longitude" json:"longitude" bson:"longitude" validate:"required,min=-180,max=180"`
+	Limit       int      `query:"limit" json:"limit" bson:"limit" validate:"omitempty,min=1,max=1000"`
+	MaxDistance float64  `query:"max_distance" json:"max_distance" bson:"max_distance" validate:"omitempty,gt=0,gtefield=MinDistance"`
+	MinDistance float64  `query:"min_distance" json:"min_distance" bson:"min_distance" validate:"omitempty,gt=0"`
+	Mode        string   `query:"mode" json:"mode" bson:"mode" validate:"omitempty,oneof=distance tour"`
+}
+
+type Coordinate struct {
+	Latitude  *float64 `json:"latitude" bson:"latitude" validate:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" bson:"longitude" validate:"required,min=-180,max=180"`
+}
+
+type PlanRouteRequest struct {
+	Start         Coordinate  `json:"start" bson:"start" validate:"required"`
+	End           *Coordinate `json:"end" bson:"end" validate:"omitempty,excluded_with=ReturnToStart"`
+	ReturnToStart bool        `json:"return_to_start" bson:"return_to_start"`
+	LocationIDs   []string    `json:"location_ids" bson:"location_ids" validate:"required,min=1,max=1000,dive,required"`
+}
+
+type MatrixPoint struct {
+	ID        string   `json:"id,omitempty" bson:"_id,omitempty" validate:"required_without_all=Latitude Longitude"`
+	Latitude  *float64 `json:"latitude,omitempty" bson:"latitude,omitempty" validate:"required_without=ID,excluded_with=ID,omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude,omitempty" bson:"longitude,omitempty" validate:"required_without=ID,excluded_with=ID,omitempty,min=-180,max=180"`
+}
+
+type DistanceMatrixRequest struct {
+	Origins      []MatrixPoint `json:"origins" bson:"origins" validate:"required,min=1,dive"`
+	Destinations []MatrixPoint `json:"destinations" bson:"destinations" validate:"required,min=1,dive"`
+}
+
+type GetNearbyLocationsRequest struct {
+	Latitude  *float64 `query:"latitude" json:"latitude" bson:"latitude" validate:"required,min=-90,max=90"`
+	Longitude *float64 `query:"longitude" json:"longitude" bson:"longitude" validate:"required,min=-180,max=180"`
+	RadiusKm  float64  `query:"radius_km" json:"radius_km" bson:"radius_km" validate:"required,gt=0"`
+	Page      int      `query:"page" json:"page" bson:"page" validate:"omitempty,min=1"`
+	Limit     int      `query:"limit" json:"limit" bson:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+// SearchLocationsRequest looks locations up by name. With both coordinates,
+// locations closer to them rank higher.
+type SearchLocationsRequest struct {
+	Query     string   `query:"q" json:"q" validate:"required,max=100"`
+	Latitude  *float64 `query:"latitude" json:"latitude" validate:"omitempty,min=-90,max=90"`
+	Longitude *float64 `query:"longitude" json:"longitude" validate:"omitempty,min=-180,max=180"`
+	Limit     int      `query:"limit" json:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type GetLocationsInBoxRequest struct {
+	MinLatitude  *float64 `query:"min_lat" json:"min_lat" bson:"min_lat" validate:"required,min=-90,max=90"`
+	MinLongitude *float64 `query:"min_lon" json:"min_lon" bson:"min_lon" validate:"required,min=-180,max=180"`
+	MaxLatitude  *float64 `query:"max_lat" json:"max_lat" bson:"max_lat" validate:"required,min=-90,max=90,gtefield=MinLatitude"`
+	MaxLongitude *float64 `query:"max_lon" json:"max_lon" bson:"max_lon" validate:"required,min=-180,max=180"`
+	Page         int      `query:"page" json:"page" bson:"page" validate:"omitempty,min=1"`
+	Limit        int      `query:"limit" json:"limit" bson:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+type GeoJSONPolygon struct {
+	Type        string        `json:"type" bson:"type" validate:"required,eq=Polygon"`
+	Coordinates [][][]float64 `json:"coordinates" bson:"coordinates" validate:"required,min=1,dive,min=4,dive,len=2"`
+}
+
+type GetLocationsInPolygonRequest struct {
+	GeoJSONPolygon
+	Page  int `query:"page" json:"-" bson:"page" validate:"omitempty,min=1"`
+	Limit int `query:"limit" json:"-" bson:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+var (
+	ErrPolygonRingNotClosed   = errors.New("polygon rings must start and end with the same position")
+	ErrPolygonCoordinateRange = errors.New("polygon positions must be [longitude, latitude] within -180..180 and -90..90")
+	ErrInvalidCSV             = errors.New("invalid csv")
+	ErrUnsupportedFormat      = errors.New("unsupported format")
+	ErrPatchMissingID         = errors.New("every location patch needs an id")
+	ErrPatchUnknownField      = errors.New("field cannot be patched")
+	ErrPatchNotRemovable      = errors.New("field cannot be removed")
+	ErrPatchEmpty             = errors.New("patch changes no field")
+	ErrPatchInvalidValue      = errors.New("invalid value")
+	ErrRevertTarget           = errors.New("either version or as_of is required, but not both")
+	ErrInvalidCursor          = errors.New("invalid cursor")
+	ErrUnknownSortField       = errors.New("locations cannot be sorted by")
+	ErrUnknownField           = errors.New("unknown field")
+	ErrSearchQuery            = errors.New("q must contain something to search for")
+	ErrSearchCoordinates      = errors.New("latitude and longitude must be given together")
+)
+
+func (req *GetLocationsRequest) ValidateLocation() error {
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if field, _ := req.SortField(); req.Sort != "" && !LocationSortFields[field] {
+		return fmt.Errorf("%w: %s", ErrUnknownSortField, field)
+	}
+
+	for _, field := range req.SelectedFields() {
+		if !LocationFields[field] {
+			return fmt.Errorf("%w: %s", ErrUnknownField, field)
+		}
+	}
+
+	return nil
+}
+
+// SortField splits Sort into the field and whether it is sorted in descending
+// order.
+func (req *GetLocationsRequest) SortField() (field string, descending bool) {
+	if strings.HasPrefix(req.Sort, "-") {
+		return req.Sort[1:], true
+	}
+
+	return req.Sort, false
+}
+
+// SelectedFields lists the fields named in Fields, or nil when all of them are
+// wanted.
+func (req *GetLocationsRequest) SelectedFields() []string {
+	var fields []string
+
+	for _, field := range strings.Split(req.Fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+func (req *GetLocationRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *GetLocationHistoryRequest) ValidateLocation() error {
+	return validate.Struct(req)
 }
 
 func (req *CreateLocationRequest) ValidateLocation() error {
 	return validate.Struct(req)
 }
 
+// ValidateLocation only checks the size of the batch; each location is validated
+// on its own so that one bad item does not reject the rest.
+func (req *CreateLocationsRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+// ValidateLocation only checks the collection itself; each feature is converted
+// and validated on its own like the items of CreateLocationsRequest.
+func (req *ImportGeoJSONRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *RevertLocationRequest) ValidateLocation() error {
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if (req.Version == nil) == (req.AsOf == "") {
+		return ErrRevertTarget
+	}
+
+	return nil
+}
+
 func (req *UpdateLocationsRequest) ValidateLocation() error {
 	return validate.Struct(req)
 }
 
+func (patch *LocationPatch) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	if id, ok := fields["id"]; ok {
+		if err := json.Unmarshal(id, &patch.ID); err != nil {
+			return fmt.Errorf("id: %w", err)
+		}
+
+		delete(fields, "id")
+	}
+
+	if version, ok := fields["expected_version"]; ok {
+		if err := json.Unmarshal(version, &patch.ExpectedVersion); err != nil {
+			return fmt.Errorf("expected_version: %w", err)
+		}
+
+		delete(fields, "expected_version")
+	}
+
+	patch.Fields = fields
+
+	return nil
+}
+
+// Changes splits the patch into the values to set and the fields to remove,
+// checking every member against locationPatchFields.
+func (patch *LocationPatch) Changes() (set map[string]interface{}, unset []string, err error) {
+	set = map[string]interface{}{}
+	unset = []string{}
+
+	for name, raw := range patch.Fields {
+		field, ok := locationPatchFields[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrPatchUnknownField, name)
+		}
+
+		if string(raw) == "null" {
+			if !field.removable {
+				return nil, nil, fmt.Errorf("%w: %s", ErrPatchNotRemovable, name)
+			}
+
+			unset = append(unset, name)
+
+			continue
+		}
+
+		value, err := field.decode(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w for %s: %s", ErrPatchInvalidValue, name, err.Error())
+		}
+
+		if err := validate.Var(value, field.tag); err != nil {
+			return nil, nil, fmt.Errorf("%w for %s: must satisfy %s", ErrPatchInvalidValue, name, field.tag)
+		}
+
+		set[name] = value
+	}
+
+	sort.Strings(unset)
+
+	return set, unset, nil
+}
+
+func (field patchField) decode(raw json.RawMessage) (interface{}, error) {
+	if field.number {
+		var number float64
+		err := json.Unmarshal(raw, &number)
+
+		return number, err
+	}
+
+	var text string
+	err := json.Unmarshal(raw, &text)
+
+	return text, err
+}
+
+func (req *PatchLocationsRequest) ValidateLocation() error {
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	for i := range req.Locations {
+		if req.Locations[i].ID == "" {
+			return ErrPatchMissingID
+		}
+
+		if version := req.Locations[i].ExpectedVersion; version != nil && *version < 1 {
+			return fmt.Errorf("location %s: %w for expected_version", req.Locations[i].ID, ErrPatchInvalidValue)
+		}
+
+		set, unset, err := req.Locations[i].Changes()
+		if err != nil {
+			return fmt.Errorf("location %s: %w", req.Locations[i].ID, err)
+		}
+
+		if len(set) == 0 && len(unset) == 0 {
+			return fmt.Errorf("location %s: %w", req.Locations[i].ID, ErrPatchEmpty)
+		}
+	}
+
+	return nil
+}
+
+func (req *DeleteLocationsRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *ArchiveLocationsRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *RestoreLocationsRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *GetArchivedLocationsRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
 func (req *GetRoutesRequest) ValidateLocation() error {
 	return validate.Struct(req)
 }
+
+func (req *PlanRouteRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *DistanceMatrixRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *GetNearbyLocationsRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *SearchLocationsRequest) ValidateLocation() error {
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		return ErrSearchQuery
+	}
+
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return ErrSearchCoordinates
+	}
+
+	return nil
+}
+
+func (req *GetLocationsInBoxRequest) ValidateLocation() error {
+	return validate.Struct(req)
+}
+
+func (req *GetLocationsInPolygonRequest) ValidateLocation() error {
+	if err := validate.Struct(req); err != nil {
+		return err
+	}
+
+	for _, ring := range req.Coordinates {
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return ErrPolygonRingNotClosed
+		}
+
+		for _, position := range ring {
+			if position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+				return ErrPolygonCoordinateRange
+			}
+		}
+	}
+
+	return nil
+}
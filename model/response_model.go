@@ -1,38 +1,206 @@
 package model
 
+import "time"
+
 type CreateLocationResponse struct {
 	ID string `json:"id" bson:"_id"`
 }
 
+type CreateLocationError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type CreateLocationsResponse struct {
+	CreatedIDs   []string              `json:"created_ids"`
+	Failed       []CreateLocationError `json:"failed"`
+	CreatedCount int64                 `json:"created_count"`
+}
+
+type ImportLocationError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportLocationsResponse struct {
+	CreatedIDs     []string              `json:"created_ids"`
+	Failed         []ImportLocationError `json:"failed"`
+	CreatedCount   int64                 `json:"created_count"`
+	ValidCount     int                   `json:"valid_count"`
+	IgnoredColumns []string              `json:"ignored_columns,omitempty"`
+	DryRun         bool                  `json:"dry_run"`
+}
+
 type GetLocationResponse struct {
-	ID          string  `json:"id" bson:"_id"`
-	Name        string  `json:"name" bson:"name"`
-	Latitude    float64 `json:"latitude" bson:"latitude"`
-	Longitude   float64 `json:"longitude" bson:"longitude"`
-	MarkerColor string  `json:"marker_color" bson:"marker_color"`
+	ID          string     `json:"id" bson:"_id"`
+	Name        string     `json:"name" bson:"name"`
+	Latitude    float64    `json:"latitude" bson:"latitude"`
+	Longitude   float64    `json:"longitude" bson:"longitude"`
+	MarkerColor string     `json:"marker_color" bson:"marker_color"`
+	Version     int64      `json:"version" bson:"version"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
 }
 
 type GetLocationsResponse struct {
-	Locations []GetLocationResponse `json:"locations"`
+	Locations  []GetLocationResponse `json:"locations"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+	Total      *int64                `json:"total,omitempty"`
 }
 
 type UpdateLocationsResponse struct {
 	UpdatedIDs   []string `json:"updated_ids"`
 	FailedIDs    []string `json:"failed_ids"`
+	ConflictIDs  []string `json:"conflict_ids"`
 	UpdatedCount int64    `json:"updated_count"`
 }
 
+type DeleteLocationResponse struct {
+	ID string `json:"id" bson:"_id"`
+}
+
+type DeleteLocationsResponse struct {
+	DeletedIDs   []string `json:"deleted_ids"`
+	FailedIDs    []string `json:"failed_ids"`
+	DeletedCount int64    `json:"deleted_count"`
+}
+
+type ArchiveLocationsResponse struct {
+	ArchivedIDs   []string `json:"archived_ids"`
+	FailedIDs     []string `json:"failed_ids"`
+	ArchivedCount int64    `json:"archived_count"`
+}
+
+type RestoreLocationsResponse struct {
+	RestoredIDs   []string `json:"restored_ids"`
+	FailedIDs     []string `json:"failed_ids"`
+	RestoredCount int64    `json:"restored_count"`
+}
+
+type ArchivedLocation struct {
+	ID          string    `json:"id" bson:"_id"`
+	Name        string    `json:"name" bson:"name"`
+	Latitude    float64   `json:"latitude" bson:"latitude"`
+	Longitude   float64   `json:"longitude" bson:"longitude"`
+	MarkerColor string    `json:"marker_color" bson:"marker_color"`
+	DeletedAt   time.Time `json:"deleted_at" bson:"deleted_at"`
+}
+
+type GetArchivedLocationsResponse struct {
+	Locations []ArchivedLocation `json:"locations"`
+}
+
+const (
+	HistoryActionCreate  = "create"
+	HistoryActionUpdate  = "update"
+	HistoryActionDelete  = "delete"
+	HistoryActionArchive = "archive"
+	HistoryActionRestore = "restore"
+	HistoryActionPurge   = "purge"
+	HistoryActionRevert  = "revert"
+)
+
+// LocationState is the part of a location that the history keeps track of.
+type LocationState struct {
+	Name        string  `json:"name" bson:"name"`
+	Latitude    float64 `json:"latitude" bson:"latitude"`
+	Longitude   float64 `json:"longitude" bson:"longitude"`
+	MarkerColor string  `json:"marker_color" bson:"marker_color"`
+}
+
+// LocationHistoryEntry records one change to a location. Before is nil for a
+// create and After is nil for a delete or purge. Version is the version the
+// change left the location at.
+type LocationHistoryEntry struct {
+	LocationID string         `json:"location_id" bson:"location_id"`
+	Action     string         `json:"action" bson:"action"`
+	Version    int64          `json:"version" bson:"version"`
+	Actor      string         `json:"actor" bson:"actor"`
+	At         time.Time      `json:"at" bson:"at"`
+	Before     *LocationState `json:"before" bson:"before"`
+	After      *LocationState `json:"after" bson:"after"`
+}
+
+type GetLocationHistoryResponse struct {
+	History []LocationHistoryEntry `json:"history"`
+}
+
 type Route struct {
+	ID                 string  `json:"id" bson:"_id"`
+	Name               string  `json:"name" bson:"name"`
+	Latitude           float64 `json:"latitude" bson:"latitude"`
+	Longitude          float64 `json:"longitude" bson:"longitude"`
+	Distance           float64 `json:"distance" bson:"distance"`
+	LegDistance        float64 `json:"leg_distance,omitempty" bson:"leg_distance,omitempty"`
+	CumulativeDistance float64 `json:"cumulative_distance,omitempty" bson:"cumulative_distance,omitempty"`
+	MarkerColor        string  `json:"marker_color" bson:"marker_color"`
+}
+
+// GetRoutesResponse carries Start for a tour, and End when the tour finishes
+// somewhere after its last stop, such as back at the start.
+type GetRoutesResponse struct {
+	Routes           []Route     `json:"routes"`
+	Start            *Coordinate `json:"start,omitempty"`
+	End              *Coordinate `json:"end,omitempty"`
+	FinalLegDistance float64     `json:"final_leg_distance,omitempty"`
+	TotalDistance    float64     `json:"total_distance,omitempty"`
+	MissingIDs       []string    `json:"missing_ids,omitempty"`
+}
+
+type NearbyLocation struct {
 	ID          string  `json:"id" bson:"_id"`
 	Name        string  `json:"name" bson:"name"`
+	Latitude    float64 `json:"latitude" bson:"latitude"`
+	Longitude   float64 `json:"longitude" bson:"longitude"`
 	Distance    float64 `json:"distance" bson:"distance"`
 	MarkerColor string  `json:"marker_color" bson:"marker_color"`
 }
 
-type GetRoutesResponse struct {
-	Routes []Route `json:"routes"`
+// LocationSearchResult is a location found by name. Score ranks it against the
+// other results and Distance, in kilometres, is only set when the search was
+// given a position.
+type LocationSearchResult struct {
+	ID          string   `json:"id" bson:"_id"`
+	Name        string   `json:"name" bson:"name"`
+	Latitude    float64  `json:"latitude" bson:"latitude"`
+	Longitude   float64  `json:"longitude" bson:"longitude"`
+	MarkerColor string   `json:"marker_color" bson:"marker_color"`
+	Score       float64  `json:"score" bson:"score"`
+	Distance    *float64 `json:"distance,omitempty" bson:"-"`
+}
+
+type SearchLocationsResponse struct {
+	Locations []LocationSearchResult `json:"locations"`
+}
+
+type GetNearbyLocationsResponse struct {
+	Locations []NearbyLocation `json:"locations"`
+}
+
+type DistanceMatrixResponse struct {
+	Origins      []MatrixPoint `json:"origins"`
+	Destinations []MatrixPoint `json:"destinations"`
+	Distances    [][]float64   `json:"distances"`
+	MissingIDs   []string      `json:"missing_ids,omitempty"`
+}
+
+type GeoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Geometry   GeoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
 }
 
-type GetAllLocationsDBResponse struct {
-	Locations []GetLocationResponse `json:"locations"`
+// GeoJSONFeatureCollection carries the paging information of GET /locations as
+// foreign members next to its features.
+type GeoJSONFeatureCollection struct {
+	Type       string           `json:"type"`
+	Features   []GeoJSONFeature `json:"features"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	Total      *int64           `json:"total,omitempty"`
 }